@@ -0,0 +1,124 @@
+package tilman
+
+import "github.com/rivo/tview"
+
+// BorderGlyphs is a set of characters to draw a window border with
+type BorderGlyphs struct {
+	Horizontal  rune
+	Vertical    rune
+	TopLeft     rune
+	TopRight    rune
+	BottomLeft  rune
+	BottomRight rune
+
+	// separators drawn to the left and right of the title, none if zero
+	TitleLeft  rune
+	TitleRight rune
+}
+
+// BorderStyle defines how the border of a window is drawn when the window is
+// focused and when it is not
+type BorderStyle struct {
+	Normal  BorderGlyphs
+	Focused BorderGlyphs
+
+	// only draw the title bar, the content of the window covers the sides
+	TitleOnly bool
+}
+
+var (
+	SingleBorderGlyphs  = BorderGlyphs{'─', '│', '┌', '┐', '└', '┘', '┤', '├'}
+	DoubleBorderGlyphs  = BorderGlyphs{'═', '║', '╔', '╗', '╚', '╝', '╡', '╞'}
+	RoundedBorderGlyphs = BorderGlyphs{'─', '│', '╭', '╮', '╰', '╯', '┤', '├'}
+	HeavyBorderGlyphs   = BorderGlyphs{'━', '┃', '┏', '┓', '┗', '┛', '┫', '┣'}
+	ASCIIBorderGlyphs   = BorderGlyphs{'-', '|', '+', '+', '+', '+', '|', '|'}
+)
+
+var (
+	// BorderSingle draws single lines, double lines when focused
+	BorderSingle = &BorderStyle{Normal: SingleBorderGlyphs, Focused: DoubleBorderGlyphs}
+	// BorderDouble draws double lines
+	BorderDouble = &BorderStyle{Normal: DoubleBorderGlyphs, Focused: DoubleBorderGlyphs}
+	// BorderRounded draws single lines with rounded corners
+	BorderRounded = &BorderStyle{Normal: RoundedBorderGlyphs, Focused: RoundedBorderGlyphs}
+	// BorderHeavy draws heavy lines
+	BorderHeavy = &BorderStyle{Normal: HeavyBorderGlyphs, Focused: HeavyBorderGlyphs}
+	// BorderASCII only uses ASCII characters, '=' when focused
+	BorderASCII = &BorderStyle{
+		Normal:  ASCIIBorderGlyphs,
+		Focused: BorderGlyphs{'=', '|', '+', '+', '+', '+', '|', '|'},
+	}
+	// BorderTitleOnly only draws the title bar with single lines, double lines
+	// when focused
+	BorderTitleOnly = &BorderStyle{Normal: SingleBorderGlyphs, Focused: DoubleBorderGlyphs, TitleOnly: true}
+)
+
+// BorderSet is the set of glyphs splitters, collapsed borders and the title
+// bars of windows without border style are drawn with. Its fields are those of
+// tview.Borders.
+type BorderSet struct {
+	Horizontal  rune
+	Vertical    rune
+	TopLeft     rune
+	TopRight    rune
+	BottomLeft  rune
+	BottomRight rune
+
+	LeftT   rune
+	RightT  rune
+	TopT    rune
+	BottomT rune
+	Cross   rune
+
+	HorizontalFocus  rune
+	VerticalFocus    rune
+	TopLeftFocus     rune
+	TopRightFocus    rune
+	BottomLeftFocus  rune
+	BottomRightFocus rune
+}
+
+// DefaultBorderSet returns a copy of the glyphs of tview.Borders
+func DefaultBorderSet() *BorderSet {
+	set := BorderSet(tview.Borders)
+	return &set
+}
+
+// orDefaultBorders returns the set, or the glyphs of tview.Borders if it is nil
+func orDefaultBorders(set *BorderSet) *BorderSet {
+	if set == nil {
+		return DefaultBorderSet()
+	}
+	return set
+}
+
+// inheritBorders passes the border set of the manager down the primitive tree
+func inheritBorders(p tview.Primitive, set *BorderSet) {
+	switch p := p.(type) {
+	case *Layout:
+		p.inheritedBorders = set
+		for _, item := range p.items {
+			inheritBorders(item.Primitive, set)
+		}
+	case *Window:
+		p.inheritedBorders = set
+	}
+}
+
+// defaultBorderStyle returns the border style of windows without style of their
+// own, the title bar drawn with the glyphs of the border set
+func defaultBorderStyle(set *BorderSet) *BorderStyle {
+	return &BorderStyle{
+		Normal:    BorderGlyphs{Horizontal: set.Horizontal},
+		Focused:   BorderGlyphs{Horizontal: set.HorizontalFocus},
+		TitleOnly: true,
+	}
+}
+
+// glyphs returns the glyph set for the given focus state
+func (b *BorderStyle) glyphs(focused bool) *BorderGlyphs {
+	if focused {
+		return &b.Focused
+	}
+	return &b.Normal
+}
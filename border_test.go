@@ -0,0 +1,103 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// drawWindow draws the window alone on a screen of its size
+func drawWindow(t *testing.T, w *Window, width, height int) tcell.SimulationScreen {
+	screen := newSimulationScreen(t, width, height)
+	w.SetRect(0, 0, width, height)
+	w.Draw(screen)
+	return screen
+}
+
+// checkCells fails the test if the cells do not show the given runes
+func checkCells(t *testing.T, screen tcell.Screen, what string, cells map[[2]int]rune) {
+	t.Helper()
+
+	for position, want := range cells {
+		if got, _, _, _ := screen.GetContent(position[0], position[1]); got != want {
+			t.Errorf("%s: the cell %v is %q, want %q", what, position, got, want)
+		}
+	}
+}
+
+func TestBorderStyles(t *testing.T) {
+	styles := map[string]*BorderStyle{
+		"single":  BorderSingle,
+		"double":  BorderDouble,
+		"rounded": BorderRounded,
+		"heavy":   BorderHeavy,
+		"ascii":   BorderASCII,
+	}
+
+	for name, style := range styles {
+		for _, focused := range []bool{false, true} {
+			w := NewWindow().SetBorder(true).SetBorderStyle(style).SetTitle("T")
+			if focused {
+				w.Focus(func(p tview.Primitive) { p.Focus(nil) })
+			}
+			screen := drawWindow(t, w, 10, 4)
+
+			glyphs := style.glyphs(focused)
+			checkCells(t, screen, name, map[[2]int]rune{
+				{0, 0}: glyphs.TopLeft,
+				{9, 0}: glyphs.TopRight,
+				{0, 3}: glyphs.BottomLeft,
+				{9, 3}: glyphs.BottomRight,
+				{1, 0}: glyphs.Horizontal,
+				{1, 3}: glyphs.Horizontal,
+				{0, 1}: glyphs.Vertical,
+				{9, 2}: glyphs.Vertical,
+				// the title is centered between the separators
+				{3, 0}: glyphs.TitleLeft,
+				{4, 0}: 'T',
+				{5, 0}: glyphs.TitleRight,
+			})
+
+			if x, y, width, height := w.GetInnerRect(); x != 1 || y != 1 || width != 8 || height != 2 {
+				t.Errorf("%s: the inner rect is %d,%d %dx%d, want 1,1 8x2", name, x, y, width, height)
+			}
+		}
+	}
+}
+
+func TestBorderTitleOnly(t *testing.T) {
+	w := NewWindow().SetBorder(true).SetBorderStyle(BorderTitleOnly)
+	screen := drawWindow(t, w, 10, 4)
+
+	checkCells(t, screen, "title only", map[[2]int]rune{
+		{0, 0}: SingleBorderGlyphs.Horizontal,
+		{9, 0}: SingleBorderGlyphs.Horizontal,
+		{0, 1}: ' ',
+		{9, 2}: ' ',
+		{0, 3}: ' ',
+	})
+	if x, y, width, height := w.GetInnerRect(); x != 0 || y != 1 || width != 10 || height != 3 {
+		t.Errorf("the inner rect is %d,%d %dx%d, want 0,1 10x3", x, y, width, height)
+	}
+}
+
+func TestSetBorder(t *testing.T) {
+	w := NewWindow().SetBorder(true)
+	w.SetRect(0, 0, 10, 4)
+
+	// the box knows about the border before the window is drawn
+	if x, y, width, height := w.GetInnerRect(); x != 1 || y != 1 || width != 8 || height != 2 {
+		t.Errorf("the inner rect is %d,%d %dx%d before the first draw, want 1,1 8x2", x, y, width, height)
+	}
+
+	w.SetBorder(false)
+	if x, y, width, height := w.GetInnerRect(); x != 0 || y != 0 || width != 10 || height != 4 {
+		t.Errorf("the inner rect is %d,%d %dx%d without border, want 0,0 10x4", x, y, width, height)
+	}
+	screen := drawWindow(t, w, 10, 4)
+	checkCells(t, screen, "no border", map[[2]int]rune{
+		{0, 1}: ' ',
+		{9, 3}: ' ',
+	})
+}
@@ -0,0 +1,302 @@
+package tilman
+
+import (
+	"errors"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// bufferCell is a cell of an off-screen buffer
+type bufferCell struct {
+	mainc rune
+	combc []rune
+	style tcell.Style
+}
+
+// Buffer implements tcell.Screen in memory, primitives draw into it off-screen
+// and the content is then composited onto another screen. Coordinates start
+// at (0,0) in the top-left corner, the buffer has no events.
+type Buffer struct {
+	width, height int
+	cells         []bufferCell
+	style         tcell.Style
+
+	cursorX, cursorY int
+	cursorShown      bool
+}
+
+// NewBuffer creates a new off-screen buffer of the given size filled with
+// blank cells
+func NewBuffer(width, height int) *Buffer {
+	b := &Buffer{style: tcell.StyleDefault}
+	b.SetSize(width, height)
+	return b
+}
+
+// SetSize resizes the buffer and clears it
+func (b *Buffer) SetSize(width, height int) {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+
+	b.width, b.height = width, height
+	if cap(b.cells) >= width*height {
+		b.cells = b.cells[:width*height]
+	} else {
+		b.cells = make([]bufferCell, width*height)
+	}
+	b.Clear()
+}
+
+// cell returns the cell at the given location, nil if it is out of range
+func (b *Buffer) cell(x, y int) *bufferCell {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return nil
+	}
+	return &b.cells[y*b.width+x]
+}
+
+// Init implements tcell.Screen.Init, a buffer needs no initialization
+func (b *Buffer) Init() error {
+	return nil
+}
+
+// Fini implements tcell.Screen.Fini
+func (b *Buffer) Fini() {}
+
+// Clear fills the buffer with blank cells of the style set by SetStyle
+func (b *Buffer) Clear() {
+	b.Fill(' ', b.style)
+}
+
+// Fill implements tcell.Screen.Fill
+func (b *Buffer) Fill(ch rune, style tcell.Style) {
+	for i := range b.cells {
+		b.cells[i] = bufferCell{mainc: ch, style: style}
+	}
+}
+
+// SetCell is an older API, and will be removed.  Please use
+// SetContent instead; SetCell is implemented in terms of SetContent.
+func (b *Buffer) SetCell(x int, y int, style tcell.Style, ch ...rune) {
+	if len(ch) > 0 {
+		b.SetContent(x, y, ch[0], ch[1:], style)
+	} else {
+		b.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// GetContent returns the contents at the given location, a blank cell out of
+// range
+func (b *Buffer) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	c := b.cell(x, y)
+	if c == nil {
+		return ' ', nil, tcell.StyleDefault, 1
+	}
+
+	width := runewidth.RuneWidth(c.mainc)
+	if width < 1 {
+		width = 1
+	}
+	return c.mainc, c.combc, c.style, width
+}
+
+// SetContent sets the contents of the given cell location, out of range
+// locations are ignored
+func (b *Buffer) SetContent(x int, y int, mainc rune, combc []rune, style tcell.Style) {
+	if c := b.cell(x, y); c != nil {
+		*c = bufferCell{mainc: mainc, combc: append([]rune(nil), combc...), style: style}
+	}
+}
+
+// SetStyle sets the style used to clear the buffer
+func (b *Buffer) SetStyle(style tcell.Style) {
+	b.style = style
+}
+
+// ShowCursor sets the position of the cursor, it is shown when the buffer is
+// composited
+func (b *Buffer) ShowCursor(x int, y int) {
+	b.cursorX, b.cursorY = x, y
+	b.cursorShown = b.cell(x, y) != nil
+}
+
+// HideCursor hides the cursor
+func (b *Buffer) HideCursor() {
+	b.cursorShown = false
+}
+
+// Size returns the size of the buffer
+func (b *Buffer) Size() (int, int) {
+	return b.width, b.height
+}
+
+// PollEvent returns nil, a buffer has no events
+func (b *Buffer) PollEvent() tcell.Event {
+	return nil
+}
+
+// PostEvent fails, a buffer has no events
+func (b *Buffer) PostEvent(ev tcell.Event) error {
+	return errors.New("buffer has no event queue")
+}
+
+// PostEventWait does nothing, a buffer has no events
+func (b *Buffer) PostEventWait(ev tcell.Event) {}
+
+// EnableMouse does nothing
+func (b *Buffer) EnableMouse() {}
+
+// DisableMouse does nothing
+func (b *Buffer) DisableMouse() {}
+
+// EnablePaste does nothing
+func (b *Buffer) EnablePaste() {}
+
+// DisablePaste does nothing
+func (b *Buffer) DisablePaste() {}
+
+// HasMouse returns false
+func (b *Buffer) HasMouse() bool {
+	return false
+}
+
+// Colors returns the number of colors a buffer holds, all of them
+func (b *Buffer) Colors() int {
+	return 1 << 24
+}
+
+// Show does nothing, the content is shown by compositing it
+func (b *Buffer) Show() {}
+
+// Sync does nothing, the content is shown by compositing it
+func (b *Buffer) Sync() {}
+
+// CharacterSet returns "UTF-8"
+func (b *Buffer) CharacterSet() string {
+	return "UTF-8"
+}
+
+// RegisterRuneFallback does nothing, the screen composited onto substitutes
+// the runes
+func (b *Buffer) RegisterRuneFallback(r rune, subst string) {}
+
+// UnregisterRuneFallback does nothing
+func (b *Buffer) UnregisterRuneFallback(r rune) {}
+
+// CanDisplay returns true, the screen composited onto substitutes the runes
+func (b *Buffer) CanDisplay(r rune, checkFallbacks bool) bool {
+	return true
+}
+
+// Resize sets the size of the buffer, the position is ignored
+func (b *Buffer) Resize(x, y, width, height int) {
+	b.SetSize(width, height)
+}
+
+// HasKey returns true
+func (b *Buffer) HasKey(k tcell.Key) bool {
+	return true
+}
+
+// Beep does nothing
+func (b *Buffer) Beep() error {
+	return nil
+}
+
+// CompositeMode defines how the cells of a buffer are combined with the cells
+// of the screen it is composited onto
+type CompositeMode int
+
+const (
+	// CompositeOpaque replaces the cells of the screen
+	CompositeOpaque CompositeMode = iota
+	// CompositeSkipSpaces leaves the cells of the screen under blank cells
+	// without background color, other cells replace them
+	CompositeSkipSpaces
+	// CompositeBlend blends the colors of the buffer with the colors of the
+	// screen by the opacity, blank cells show the characters of the screen
+	CompositeBlend
+)
+
+// Composite draws the content of the buffer onto the screen with its top-left
+// corner at the given position. The opacity from 0 to 1 is used to blend.
+func (b *Buffer) Composite(screen tcell.Screen, x, y int, mode CompositeMode, opacity float64) {
+	for row := 0; row < b.height; row++ {
+		for column := 0; column < b.width; column++ {
+			c := b.cell(column, row)
+			fg, bg, _ := c.style.Decompose()
+
+			// the cell after a wide character is covered by it
+			if column > 0 && runewidth.RuneWidth(b.cell(column-1, row).mainc) > 1 {
+				continue
+			}
+
+			switch mode {
+			case CompositeSkipSpaces:
+				if c.mainc == ' ' && bg == tcell.ColorDefault {
+					continue
+				}
+				screen.SetContent(x+column, y+row, c.mainc, c.combc, c.style)
+
+			case CompositeBlend:
+				mainc, combc, below, _ := screen.GetContent(x+column, y+row)
+				belowFg, belowBg, _ := below.Decompose()
+
+				style := c.style
+				if blended, ok := blendColors(belowBg, bg, opacity); ok {
+					style = style.Background(blended)
+				}
+				if c.mainc == ' ' {
+					// the character below shows through, faded toward the layer
+					if blended, ok := blendColors(belowFg, bg, opacity); ok {
+						style = style.Foreground(blended)
+					}
+					screen.SetContent(x+column, y+row, mainc, combc, style)
+					continue
+				}
+				if blended, ok := blendColors(belowBg, fg, opacity); ok {
+					style = style.Foreground(blended)
+				}
+				screen.SetContent(x+column, y+row, c.mainc, c.combc, style)
+
+			default:
+				screen.SetContent(x+column, y+row, c.mainc, c.combc, c.style)
+			}
+		}
+	}
+
+	if b.cursorShown {
+		screen.ShowCursor(x+b.cursorX, y+b.cursorY)
+	}
+}
+
+// drawShadow darkens the cells of the screen to the right and below the given
+// rectangle, cells without RGB colors are dimmed
+func drawShadow(screen tcell.Screen, x, y, width, height int) {
+	darken := func(x, y int) {
+		mainc, combc, style, _ := screen.GetContent(x, y)
+		fg, bg, _ := style.Decompose()
+		if bg == tcell.ColorDefault {
+			bg = tcell.ColorBlack
+		}
+		shaded, okBg := blendColors(bg, tcell.ColorBlack, 0.6)
+		faded, okFg := blendColors(fg, tcell.ColorBlack, 0.6)
+		if !okBg || !okFg {
+			screen.SetContent(x, y, mainc, combc, style.Dim(true))
+			return
+		}
+		screen.SetContent(x, y, mainc, combc, style.Foreground(faded).Background(shaded))
+	}
+
+	for row := y + 1; row <= y+height; row++ {
+		darken(x+width, row)
+	}
+	for column := x + 1; column < x+width; column++ {
+		darken(column, y+height)
+	}
+}
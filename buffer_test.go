@@ -0,0 +1,135 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer(4, 2)
+	if width, height := b.Size(); width != 4 || height != 2 {
+		t.Errorf("the buffer is %dx%d, want 4x2", width, height)
+	}
+
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed)
+	b.SetContent(1, 0, 'a', []rune{'\u0301'}, style)
+	b.SetContent(2, 1, '日', nil, tcell.StyleDefault)
+	b.SetContent(4, 0, 'x', nil, tcell.StyleDefault)
+
+	if mainc, combc, got, width := b.GetContent(1, 0); mainc != 'a' || len(combc) != 1 || got != style || width != 1 {
+		t.Errorf("the cell 1,0 is %q %q %v with width %d", mainc, combc, got, width)
+	}
+	if mainc, _, _, width := b.GetContent(2, 1); mainc != '日' || width != 2 {
+		t.Errorf("the wide cell is %q with width %d, want width 2", mainc, width)
+	}
+	if mainc, _, _, width := b.GetContent(4, 0); mainc != ' ' || width != 1 {
+		t.Errorf("the cell out of range is %q with width %d, want a blank cell", mainc, width)
+	}
+
+	// resizing clears the buffer with the style
+	blue := tcell.StyleDefault.Background(tcell.ColorBlue)
+	b.SetStyle(blue)
+	b.SetSize(3, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			if mainc, _, got, _ := b.GetContent(x, y); mainc != ' ' || got != blue {
+				t.Errorf("the cell %d,%d is %q %v after resizing, want a blank blue cell", x, y, mainc, got)
+			}
+		}
+	}
+
+	// the cursor is shown where the buffer is composited
+	screen := newSimulationScreen(t, 10, 5)
+	b.ShowCursor(1, 2)
+	b.Composite(screen, 4, 1, CompositeOpaque, 1)
+	if x, y, visible := screen.GetCursor(); x != 5 || y != 3 || !visible {
+		t.Errorf("the cursor is at %d,%d visible %v, want 5,3 visible", x, y, visible)
+	}
+	b.ShowCursor(5, 0)
+	screen.HideCursor()
+	b.Composite(screen, 4, 1, CompositeOpaque, 1)
+	if _, _, visible := screen.GetCursor(); visible {
+		t.Error("the cursor out of the buffer is shown")
+	}
+}
+
+func TestComposite(t *testing.T) {
+	black := tcell.NewRGBColor(0, 0, 0)
+	white := tcell.NewRGBColor(255, 255, 255)
+	below := tcell.StyleDefault.Foreground(black).Background(black)
+
+	// a 3x1 buffer with a character, a blank cell and a blank cell with a
+	// background color
+	b := NewBuffer(3, 1)
+	b.SetContent(0, 0, 'a', nil, tcell.StyleDefault.Foreground(white).Background(white))
+	b.SetContent(2, 0, ' ', nil, tcell.StyleDefault.Background(white))
+
+	composite := func(mode CompositeMode, opacity float64) tcell.SimulationScreen {
+		screen := newSimulationScreen(t, 3, 1)
+		screen.Fill('x', below)
+		b.Composite(screen, 0, 0, mode, opacity)
+		return screen
+	}
+
+	screen := composite(CompositeOpaque, 1)
+	checkCells(t, screen, "opaque", map[[2]int]rune{{0, 0}: 'a', {1, 0}: ' ', {2, 0}: ' '})
+
+	screen = composite(CompositeSkipSpaces, 1)
+	checkCells(t, screen, "skip spaces", map[[2]int]rune{{0, 0}: 'a', {1, 0}: 'x', {2, 0}: ' '})
+	if cellStyle(screen, 1, 0) != below {
+		t.Error("the skipped cell changed its style")
+	}
+
+	// the characters below blank cells show through, the colors are mixed
+	screen = composite(CompositeBlend, 0.5)
+	checkCells(t, screen, "blend", map[[2]int]rune{{0, 0}: 'a', {1, 0}: 'x', {2, 0}: 'x'})
+	gray := tcell.NewRGBColor(127, 127, 127)
+	if _, bg, _ := cellStyle(screen, 0, 0).Decompose(); bg != gray {
+		t.Errorf("the blended background is %v, want %v", bg, gray)
+	}
+	if fg, _, _ := cellStyle(screen, 2, 0).Decompose(); fg != gray {
+		t.Errorf("the character below is drawn in %v, want %v", fg, gray)
+	}
+}
+
+func TestCompositeWideCharacters(t *testing.T) {
+	// the cell after the wide character is covered and not composited
+	b := NewBuffer(3, 1)
+	b.SetContent(0, 0, '日', nil, tcell.StyleDefault)
+	b.SetContent(1, 0, 'z', nil, tcell.StyleDefault)
+	b.SetContent(2, 0, 'a', nil, tcell.StyleDefault)
+
+	screen := newSimulationScreen(t, 3, 1)
+	b.Composite(screen, 0, 0, CompositeOpaque, 1)
+	checkCells(t, screen, "wide character", map[[2]int]rune{{0, 0}: '日', {1, 0}: ' ', {2, 0}: 'a'})
+}
+
+func TestShadow(t *testing.T) {
+	white := tcell.NewRGBColor(255, 255, 255)
+	style := tcell.StyleDefault.Foreground(white).Background(white)
+	screen := newSimulationScreen(t, 10, 5)
+	screen.Fill('x', style)
+
+	// the shadow of a 3x2 rectangle at 1,1 is offset by one cell
+	drawShadow(screen, 1, 1, 3, 2)
+	shaded := map[[2]int]bool{{4, 2}: true, {4, 3}: true, {2, 3}: true, {3, 3}: true}
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 10; x++ {
+			got := cellStyle(screen, x, y) != style
+			if got != shaded[[2]int{x, y}] {
+				t.Errorf("the cell %d,%d is shaded %v, want %v", x, y, got, shaded[[2]int{x, y}])
+			}
+		}
+	}
+	if _, bg, _ := cellStyle(screen, 4, 2).Decompose(); bg != tcell.NewRGBColor(102, 102, 102) {
+		t.Errorf("the shaded background is %v", bg)
+	}
+
+	// without RGB colors the cells are dimmed
+	screen.Fill('x', tcell.StyleDefault)
+	drawShadow(screen, 1, 1, 3, 2)
+	if _, _, attributes := cellStyle(screen, 4, 2).Decompose(); attributes&tcell.AttrDim == 0 {
+		t.Error("the shadow over the default colors is not dimmed")
+	}
+}
@@ -0,0 +1,378 @@
+package tilman
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// TooltipDelay is how long the mouse has to rest on a window button before its
+// tooltip is shown
+var TooltipDelay = 500 * time.Millisecond
+
+// text returns the label of the button, or its symbol if it has no label
+func (b *WindowButton) text() string {
+	if b.Label != "" {
+		return b.Label
+	}
+	return string(b.Symbol)
+}
+
+// buttonText returns the button as drawn on the title bar, with brackets
+func (w *Window) buttonText(button *WindowButton) string {
+	return tview.Escape(w.buttonBrackets[0] + button.text() + w.buttonBrackets[1])
+}
+
+// layoutButtons calculates the position and the width of the visible buttons
+// on the title bar and the footer. It is called on every draw, so labels
+// changed at runtime move the other buttons.
+func (w *Window) layoutButtons() {
+	// offsets from the left and the right on the title bar and the footer
+	offsetLeft, offsetRight := [2]int{1, 1}, [2]int{-1, -1}
+	for _, button := range w.buttons {
+		if button.Hidden {
+			continue
+		}
+
+		row := 0
+		button.offsetY = 0
+		if button.Footer {
+			row = 1
+			button.offsetY = -1
+		}
+
+		button.width = tview.TaggedStringWidth(w.buttonText(button))
+		if button.Alignment == WindowButtonAlignRight {
+			offsetRight[row] -= button.width
+			button.offsetX = offsetRight[row]
+		} else {
+			button.offsetX = offsetLeft[row]
+			offsetLeft[row] += button.width
+		}
+	}
+}
+
+// buttonShown returns true if the button is drawn, title bar buttons need the
+// border and footer buttons the footer or the bottom border
+func (w *Window) buttonShown(button *WindowButton) bool {
+	if button.Hidden {
+		return false
+	}
+	if button.Footer {
+		return w.hasFooterRow()
+	}
+	return w.border
+}
+
+// buttonPosition returns the screen position of the first cell of the button
+func (w *Window) buttonPosition(button *WindowButton) (int, int) {
+	x, y, width, height := w.GetRect()
+
+	buttonX, buttonY := button.offsetX+x, button.offsetY+y
+	if button.offsetX < 0 {
+		buttonX += width
+	}
+	if button.offsetY < 0 {
+		buttonY += height
+	}
+
+	return buttonX, buttonY
+}
+
+// freeSpan narrows the columns [start, end) of the title bar or the footer to
+// the space between the left and the right aligned buttons, keeping a cell
+// between them and the text
+func (w *Window) freeSpan(footer bool, start, end int) (int, int) {
+	w.layoutButtons()
+	for _, button := range w.buttons {
+		if button.Footer != footer || button.Hidden {
+			continue
+		}
+
+		buttonX, _ := w.buttonPosition(button)
+		if button.Alignment == WindowButtonAlignRight {
+			if buttonX-1 < end {
+				end = buttonX - 1
+			}
+		} else if buttonX+button.width+1 > start {
+			start = buttonX + button.width + 1
+		}
+	}
+
+	return start, end
+}
+
+// GetButtonRect returns the screen position and the width of the i-th button
+// as laid out by the last draw. It returns false if the button is not shown.
+func (w *Window) GetButtonRect(i int) (int, int, int, bool) {
+	if i < 0 || i >= len(w.buttons) || !w.buttonShown(w.buttons[i]) {
+		return 0, 0, 0, false
+	}
+
+	w.layoutButtons()
+	x, y := w.buttonPosition(w.buttons[i])
+	return x, y, w.buttons[i].width, true
+}
+
+// buttonAt returns the visible button at the given screen position, if any
+func (w *Window) buttonAt(x, y int) *WindowButton {
+	w.layoutButtons()
+	for _, button := range w.buttons {
+		if !w.buttonShown(button) {
+			continue
+		}
+		if buttonX, buttonY := w.buttonPosition(button); x >= buttonX && x < buttonX+button.width && y == buttonY {
+			return button
+		}
+	}
+
+	return nil
+}
+
+// setHover sets the button under the mouse, it returns true if it changed
+func (w *Window) setHover(button *WindowButton) bool {
+	if button == w.hovered {
+		return false
+	}
+
+	w.hovered = button
+	w.hoverStart = time.Now()
+
+	if w.tooltipTimer != nil {
+		w.tooltipTimer.Stop()
+		w.tooltipTimer = nil
+	}
+	if button != nil && button.Tooltip != "" && w.changed != nil {
+		w.tooltipTimer = time.AfterFunc(TooltipDelay, w.changed)
+	}
+
+	return true
+}
+
+// handleButtonMouse updates the state of the buttons and clicks them. A button
+// is clicked when the mouse button is released over the button it was pressed
+// on. It returns true if the event was consumed.
+func (w *Window) handleButtonMouse(action tview.MouseAction, event *tcell.EventMouse) bool {
+	x, y := event.Position()
+	button := w.buttonAt(x, y)
+	enabled := button != nil && !button.Disabled
+
+	switch action {
+	case tview.MouseMove:
+		hover := button
+		if !enabled {
+			hover = nil
+		}
+		// consume the event to redraw the hovered button
+		return w.setHover(hover) && w.InRect(x, y) || enabled
+
+	case tview.MouseLeftDown:
+		w.pressed = nil
+		if enabled {
+			w.pressed = button
+		}
+		return button != nil
+
+	case tview.MouseLeftUp:
+		if pressed := w.pressed; pressed != nil {
+			w.pressed = nil
+			if button == pressed {
+				w.click(pressed)
+			}
+			return true
+		}
+		return button != nil
+
+	case tview.MouseLeftClick, tview.MouseLeftDoubleClick:
+		return button != nil
+	}
+
+	return false
+}
+
+// releaseButtons releases the pressed buttons of all windows, after the windows
+// handled the release of the mouse button
+func (m *Manager) releaseButtons() {
+	windows := collectWindows(m.visibleRoot)
+	for _, p := range m.layerPrimitives() {
+		windows = append(windows, collectWindows(p)...)
+	}
+
+	for _, w := range windows {
+		w.pressed = nil
+	}
+}
+
+// drawButtons draws the visible buttons and the tooltip of the hovered one
+func (w *Window) drawButtons(screen tcell.Screen) {
+	theme := w.currentTheme()
+	w.layoutButtons()
+
+	for _, button := range w.buttons {
+		if !w.buttonShown(button) {
+			continue
+		}
+
+		style := theme.Button
+		switch {
+		case button.Disabled:
+			style = theme.ButtonDisabled
+		case button == w.pressed:
+			style = theme.ButtonPressed
+		case button == w.hovered, button == w.selected:
+			style = theme.ButtonHover
+		}
+
+		// render the window title buttons
+		buttonX, buttonY := w.buttonPosition(button)
+		styledPrint(screen, w.buttonText(button), buttonX, buttonY, button.width, tview.AlignLeft, style)
+	}
+
+	if w.tooltipShown() {
+		w.drawTooltip(screen, w.hovered, theme.Overlay)
+	}
+}
+
+// tooltipShown returns true if the tooltip of the hovered button is shown
+func (w *Window) tooltipShown() bool {
+	button := w.hovered
+	return button != nil && w.buttonShown(button) && button.Tooltip != "" &&
+		w.pressed == nil && time.Since(w.hoverStart) >= TooltipDelay
+}
+
+// drawTooltip draws the tooltip of the button next to it, inside the window
+func (w *Window) drawTooltip(screen tcell.Screen, button *WindowButton, style tcell.Style) {
+	x, y, width, height := w.GetRect()
+	buttonX, buttonY := w.buttonPosition(button)
+
+	text := tview.Escape(button.Tooltip)
+	textWidth := tview.TaggedStringWidth(text) + 2
+	if textWidth > width {
+		textWidth = width
+	}
+
+	// below the button, or above it on the bottom border
+	tooltipY := buttonY + 1
+	if tooltipY >= y+height {
+		tooltipY = buttonY - 1
+	}
+
+	// aligned with the button, moved left to stay inside the window
+	tooltipX := buttonX
+	if tooltipX+textWidth > x+width {
+		tooltipX = x + width - textWidth
+	}
+	if tooltipX < x {
+		tooltipX = x
+	}
+
+	for x_ := tooltipX; x_ < tooltipX+textWidth; x_++ {
+		screen.SetContent(x_, tooltipY, ' ', nil, style)
+	}
+	styledPrint(screen, text, tooltipX+1, tooltipY, textWidth-2, tview.AlignLeft, style)
+}
+
+// click invokes the callback of the button
+func (w *Window) click(button *WindowButton) {
+	if button.OnClick != nil {
+		button.OnClick(w, button)
+	}
+}
+
+// FocusTitleBar selects the first button of the title bar, the arrow keys then
+// move through the buttons, Enter clicks the selected one and Escape or Tab
+// return to the content of the window. It does nothing if the window has no
+// button which can be clicked.
+func (w *Window) FocusTitleBar() *Window {
+	w.focusTitleBar()
+	return w
+}
+
+// HasTitleBarFocus returns true if a title bar button is selected with the
+// keyboard
+func (w *Window) HasTitleBarFocus() bool {
+	return w.selected != nil
+}
+
+// focusTitleBar selects the first button, it returns false if there is none
+func (w *Window) focusTitleBar() bool {
+	buttons := w.orderedButtons()
+	if len(buttons) == 0 {
+		return false
+	}
+
+	w.selected = buttons[0]
+	return true
+}
+
+// orderedButtons returns the buttons which can be clicked from left to right,
+// the title bar buttons before the footer buttons
+func (w *Window) orderedButtons() []*WindowButton {
+	var buttons []*WindowButton
+	for _, footer := range []bool{false, true} {
+		var left, right []*WindowButton
+		for _, button := range w.buttons {
+			if button.Footer != footer || !w.buttonShown(button) || button.Disabled {
+				continue
+			}
+
+			if button.Alignment == WindowButtonAlignRight {
+				// right aligned buttons are placed from right to left
+				right = append([]*WindowButton{button}, right...)
+			} else {
+				left = append(left, button)
+			}
+		}
+		buttons = append(append(buttons, left...), right...)
+	}
+
+	return buttons
+}
+
+// handleButtonKey clicks the button bound to the key and moves through the
+// buttons while the title bar has focus. It returns true if the key was
+// consumed.
+func (w *Window) handleButtonKey(event *tcell.EventKey) bool {
+	for _, button := range w.buttons {
+		if w.buttonShown(button) && !button.Disabled && !button.Key.IsZero() && button.Key.Matches(event) {
+			w.click(button)
+			return true
+		}
+	}
+
+	if w.selected == nil {
+		return false
+	}
+
+	buttons := w.orderedButtons()
+	current := -1
+	for i, button := range buttons {
+		if button == w.selected {
+			current = i
+		}
+	}
+	if current < 0 {
+		// the selected button was hidden or disabled meanwhile
+		w.selected = nil
+		return false
+	}
+
+	switch event.Key() {
+	case tcell.KeyLeft, tcell.KeyUp:
+		w.selected = buttons[(current+len(buttons)-1)%len(buttons)]
+	case tcell.KeyRight, tcell.KeyDown:
+		w.selected = buttons[(current+1)%len(buttons)]
+	case tcell.KeyHome:
+		w.selected = buttons[0]
+	case tcell.KeyEnd:
+		w.selected = buttons[len(buttons)-1]
+	case tcell.KeyEnter:
+		w.click(w.selected)
+	case tcell.KeyEscape, tcell.KeyTab, tcell.KeyBacktab:
+		w.selected = nil
+	}
+
+	// the content does not receive keys while the title bar has focus
+	return true
+}
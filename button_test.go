@@ -0,0 +1,252 @@
+package tilman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// mouse sends a mouse event to the window, it returns whether the window
+// consumed it
+func mouse(w *Window, action tview.MouseAction, x, y int) bool {
+	event := tcell.NewEventMouse(x, y, tcell.ButtonNone, tcell.ModNone)
+	consumed, _ := w.MouseHandler()(action, event, func(p tview.Primitive) {})
+	return consumed
+}
+
+// buttonCell returns a screen position on the i-th button of the window
+func buttonCell(w *Window, i int) (int, int) {
+	w.layoutButtons()
+	return w.buttonPosition(w.buttons[i])
+}
+
+// newButtonWindow creates a bordered 20x5 window with a right aligned button
+// counting its clicks
+func newButtonWindow(clicks *int) *Window {
+	w := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).
+		AddButton('x', WindowButtonAlignRight, func(w *Window, b *WindowButton) {
+			*clicks++
+		})
+	w.SetRect(0, 0, 20, 5)
+	return w
+}
+
+func TestButtonClick(t *testing.T) {
+	clicks := 0
+	w := newButtonWindow(&clicks)
+	x, y := buttonCell(w, 0)
+
+	// released over the button it was pressed on
+	mouse(w, tview.MouseLeftDown, x, y)
+	if w.pressed != w.buttons[0] {
+		t.Error("the button is not pressed")
+	}
+	mouse(w, tview.MouseLeftUp, x, y)
+	if clicks != 1 || w.pressed != nil {
+		t.Errorf("the button was clicked %d times, want 1", clicks)
+	}
+
+	// released somewhere else
+	mouse(w, tview.MouseLeftDown, x, y)
+	mouse(w, tview.MouseLeftUp, x-5, y+2)
+	if clicks != 1 || w.pressed != nil {
+		t.Errorf("the button released outside was clicked, %d clicks", clicks)
+	}
+
+	w.buttons[0].Disabled = true
+	mouse(w, tview.MouseLeftDown, x, y)
+	mouse(w, tview.MouseLeftUp, x, y)
+	if clicks != 1 {
+		t.Error("the disabled button was clicked")
+	}
+
+	w.buttons[0].Disabled, w.buttons[0].Hidden = false, true
+	mouse(w, tview.MouseLeftDown, x, y)
+	mouse(w, tview.MouseLeftUp, x, y)
+	if clicks != 1 {
+		t.Error("the hidden button was clicked")
+	}
+}
+
+func TestButtonStates(t *testing.T) {
+	clicks := 0
+	w := newButtonWindow(&clicks)
+	theme := w.currentTheme()
+	x, y := buttonCell(w, 0)
+
+	tests := []struct {
+		name   string
+		action tview.MouseAction
+		x      int
+		style  tcell.Style
+	}{
+		{"normal", tview.MouseMove, 2, theme.Button},
+		{"hovered", tview.MouseMove, x, theme.ButtonHover},
+		{"pressed", tview.MouseLeftDown, x, theme.ButtonPressed},
+		{"released", tview.MouseLeftUp, x, theme.ButtonHover},
+		{"left", tview.MouseMove, 2, theme.Button},
+	}
+
+	for _, test := range tests {
+		mouse(w, test.action, test.x, y)
+		screen := drawWindow(t, w, 20, 5)
+		if got := cellStyle(screen, x, y); got != test.style {
+			t.Errorf("the %s button is drawn with %v, want %v", test.name, got, test.style)
+		}
+	}
+
+	w.buttons[0].Disabled = true
+	screen := drawWindow(t, w, 20, 5)
+	if got := cellStyle(screen, x, y); got != theme.ButtonDisabled {
+		t.Errorf("the disabled button is drawn with %v, want %v", got, theme.ButtonDisabled)
+	}
+
+	w.buttons[0].Hidden = true
+	screen = drawWindow(t, w, 20, 5)
+	if strings.Contains(screenText(screen), "x") {
+		t.Errorf("the hidden button is drawn:\n%s", screenText(screen))
+	}
+}
+
+func TestButtonTooltip(t *testing.T) {
+	delay := TooltipDelay
+	defer func() { TooltipDelay = delay }()
+	TooltipDelay = 0
+
+	clicks := 0
+	w := newButtonWindow(&clicks)
+	w.buttons[0].Tooltip = "Close"
+	x, y := buttonCell(w, 0)
+
+	mouse(w, tview.MouseMove, x, y)
+	screen := drawWindow(t, w, 20, 5)
+	lines := strings.Split(screenText(screen), "\n")
+	if !strings.Contains(lines[y+1], "Close") {
+		t.Errorf("the tooltip is not shown below the button:\n%s", screenText(screen))
+	}
+
+	// not while the button is pressed, nor once the mouse left
+	mouse(w, tview.MouseLeftDown, x, y)
+	if screen = drawWindow(t, w, 20, 5); strings.Contains(screenText(screen), "Close") {
+		t.Errorf("the tooltip is shown while the button is pressed:\n%s", screenText(screen))
+	}
+	mouse(w, tview.MouseLeftUp, x, y)
+	mouse(w, tview.MouseMove, 2, 2)
+	if screen = drawWindow(t, w, 20, 5); strings.Contains(screenText(screen), "Close") {
+		t.Errorf("the tooltip is shown after the mouse left:\n%s", screenText(screen))
+	}
+}
+
+func TestButtonLabels(t *testing.T) {
+	w := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).
+		AddLabeledButton("Save", WindowButtonAlignLeft, nil).
+		AddLabeledButton("保存", WindowButtonAlignLeft, nil).
+		AddButton('x', WindowButtonAlignRight, nil)
+	w.SetRect(0, 0, 30, 5)
+	w.layoutButtons()
+
+	tests := []struct {
+		x, width int
+	}{
+		{1, 6},  // [Save]
+		{7, 6},  // [保存], two cells per character
+		{26, 3}, // [x]
+	}
+	for i, test := range tests {
+		x, y := w.buttonPosition(w.buttons[i])
+		if x != test.x || y != 0 || w.buttons[i].width != test.width {
+			t.Errorf("button %d is at %d,%d with width %d, want %d,0 with width %d",
+				i, x, y, w.buttons[i].width, test.x, test.width)
+		}
+		// the whole button including the brackets can be clicked
+		for column := test.x; column < test.x+test.width; column++ {
+			if w.buttonAt(column, 0) != w.buttons[i] {
+				t.Errorf("button %d is not found at column %d", i, column)
+			}
+		}
+	}
+	if w.buttonAt(13, 0) != nil || w.buttonAt(25, 0) != nil {
+		t.Error("a button is found outside of the buttons")
+	}
+
+	screen := drawWindow(t, w, 30, 5)
+	if line := strings.Split(screenText(screen), "\n")[0]; !strings.HasPrefix(line[len("─"):], "[Save][保") {
+		t.Errorf("the title bar is %q", line)
+	}
+
+	// a changed label moves the buttons after it
+	w.buttons[0].Label = "Save as"
+	drawWindow(t, w, 30, 5)
+	if x, _ := w.buttonPosition(w.buttons[1]); x != 10 {
+		t.Errorf("the second button is at column %d after the label changed, want 10", x)
+	}
+
+	w.SetButtonBrackets("", "")
+	w.layoutButtons()
+	if x, _ := w.buttonPosition(w.buttons[1]); x != 8 || w.buttons[2].width != 1 {
+		t.Errorf("the buttons were not laid out again without brackets")
+	}
+	screen = drawWindow(t, w, 30, 5)
+	checkCells(t, screen, "no brackets", map[[2]int]rune{
+		{1, 0}:  'S',
+		{8, 0}:  '保',
+		{28, 0}: 'x',
+	})
+}
+
+func TestButtonKeys(t *testing.T) {
+	var clicked []string
+	click := func(w *Window, b *WindowButton) { clicked = append(clicked, b.Label) }
+
+	received := 0
+	root := tview.NewBox().SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		received++
+		return event
+	})
+	w := NewWindow().SetRoot(root).SetBorder(true).
+		AddLabeledButton("a", WindowButtonAlignLeft, click).
+		AddLabeledButton("b", WindowButtonAlignLeft, click).
+		AddLabeledButton("c", WindowButtonAlignRight, click).
+		AddLabeledButton("d", WindowButtonAlignRight, click)
+	w.SetRect(0, 0, 30, 5)
+	w.GetButton(0).Key, _ = ParseKeyBinding("Alt+A")
+	w.GetButton(1).Key, _ = ParseKeyBinding("F2")
+	w.GetButton(1).Disabled = true
+
+	key := func(key tcell.Key, r rune, modifiers tcell.ModMask) {
+		w.InputHandler()(tcell.NewEventKey(key, r, modifiers), func(p tview.Primitive) {})
+	}
+
+	key(tcell.KeyRune, 'A', tcell.ModAlt|tcell.ModShift)
+	key(tcell.KeyF2, 0, tcell.ModNone)
+	if strings.Join(clicked, "") != "a" || received != 1 {
+		t.Errorf("the shortcuts clicked %q and the content received %d keys, want \"a\" and 1", clicked, received)
+	}
+
+	// the disabled button is skipped, the right aligned ones go from the right
+	clicked, received = nil, 0
+	w.FocusTitleBar()
+	for _, k := range []tcell.Key{tcell.KeyEnter, tcell.KeyRight, tcell.KeyEnter, tcell.KeyRight, tcell.KeyEnter,
+		tcell.KeyRight, tcell.KeyEnter, tcell.KeyLeft, tcell.KeyEnter, tcell.KeyHome, tcell.KeyEnter} {
+		key(k, 0, tcell.ModNone)
+	}
+	if strings.Join(clicked, "") != "adcaca" || received != 0 {
+		t.Errorf("the title bar clicked %q and the content received %d keys, want \"adcadc\" and 0", clicked, received)
+	}
+
+	key(tcell.KeyEscape, 0, tcell.ModNone)
+	if w.HasTitleBarFocus() {
+		t.Error("the title bar has focus after Escape")
+	}
+	key(tcell.KeyEnter, 0, tcell.ModNone)
+	if len(clicked) != 6 || received != 1 {
+		t.Errorf("Enter was not passed to the content after Escape")
+	}
+
+	// nothing to select
+	if NewWindow().FocusTitleBar().HasTitleBarFocus() {
+		t.Error("a window without buttons has title bar focus")
+	}
+}
@@ -0,0 +1,274 @@
+package tilman
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/tview"
+)
+
+// CaptureFormat is the format a rendering is captured in
+type CaptureFormat int
+
+const (
+	// CaptureText is plain text without colors
+	CaptureText CaptureFormat = iota
+	// CaptureANSI is text with ANSI escape sequences for colors and attributes
+	CaptureANSI
+	// CaptureHTML is a standalone HTML page with colors and attributes
+	CaptureHTML
+)
+
+// extension returns the file name extension of the format
+func (f CaptureFormat) extension() string {
+	switch f {
+	case CaptureANSI:
+		return ".ans"
+	case CaptureHTML:
+		return ".html"
+	}
+	return ".txt"
+}
+
+// render draws the manager off-screen, the caller holds the lock
+func (m *Manager) render() *Buffer {
+	x, y, width, height := m.GetRect()
+	buffer := NewBuffer(width, height)
+	m.draw(NewLocalClipRegion(buffer, -x, -y, x+width, y+height))
+	return buffer
+}
+
+// Capture returns the current rendering of the manager in the given format.
+// It locks the manager, see Manager.
+func (m *Manager) Capture(format CaptureFormat) string {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.render().Capture(format)
+}
+
+// CaptureWindow returns the current rendering of the window in the given
+// format, as last laid out by the manager. It locks the manager, see Manager.
+func (m *Manager) CaptureWindow(w *Window, format CaptureFormat) string {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.captureWindow(w, format)
+}
+
+// captureWindow renders the window off-screen, the caller holds the lock. The
+// window is exposed so that it draws all its cells with damage tracking.
+func (m *Manager) captureWindow(w *Window, format CaptureFormat) string {
+	x, y, width, height := w.GetRect()
+	buffer := NewBuffer(width, height)
+	expose(w, true)
+	w.Draw(NewLocalClipRegion(buffer, -x, -y, x+width, y+height))
+	return buffer.Capture(format)
+}
+
+// SetCaptureOptions sets the directory and the format of the files the capture
+// action writes, and the function called if writing fails
+func (m *Manager) SetCaptureOptions(dir string, format CaptureFormat, onError func(err error)) *Manager {
+	m.captureDir = dir
+	m.captureFormat = format
+	m.captureOnError = onError
+	return m
+}
+
+// CaptureToFile writes the current rendering of the manager to a timestamped
+// file in the directory, e.g. "tilman-20210217-110421.html", with a counter
+// appended if the file exists, e.g. "tilman-20210217-110421-1.html". It
+// returns the path of the file. It locks the manager, see Manager.
+func (m *Manager) CaptureToFile(dir string, format CaptureFormat) (string, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.captureToFile(dir, format)
+}
+
+// captureToFile writes the rendering to a file, the caller holds the lock
+func (m *Manager) captureToFile(dir string, format CaptureFormat) (string, error) {
+	return writeCapture(m.render(), dir, format)
+}
+
+// writeCapture writes the buffer to a new timestamped file in the directory,
+// an existing file is never overwritten
+func writeCapture(buffer *Buffer, dir string, format CaptureFormat) (string, error) {
+	base := filepath.Join(dir, "tilman-"+time.Now().Format("20060102-150405"))
+
+	for i := 0; ; i++ {
+		path := base + format.extension()
+		if i > 0 {
+			path = base + "-" + strconv.Itoa(i) + format.extension()
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		_, err = f.WriteString(buffer.Capture(format))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+}
+
+// Capture returns the content of the buffer in the given format
+func (b *Buffer) Capture(format CaptureFormat) string {
+	var out strings.Builder
+
+	if format == CaptureHTML {
+		out.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+			"<style>pre { font-family: monospace; line-height: 1.2; }</style>\n" +
+			"</head>\n<body>\n<pre>")
+	}
+
+	for y := 0; y < b.height; y++ {
+		var line strings.Builder
+		last, open := tcell.StyleDefault, false
+
+		for x := 0; x < b.width; x++ {
+			c := b.cell(x, y)
+
+			// the cell after a wide character is covered by it
+			if x > 0 && runewidth.RuneWidth(b.cell(x-1, y).mainc) > 1 {
+				continue
+			}
+
+			text := string(c.mainc) + string(c.combc)
+			switch format {
+			case CaptureANSI:
+				if c.style != last || x == 0 {
+					line.WriteString(ansiStyle(c.style))
+					last = c.style
+				}
+				line.WriteString(text)
+			case CaptureHTML:
+				if c.style != last || !open {
+					if open {
+						line.WriteString("</span>")
+					}
+					line.WriteString(`<span style="` + cssStyle(c.style) + `">`)
+					last, open = c.style, true
+				}
+				line.WriteString(html.EscapeString(text))
+			default:
+				line.WriteString(text)
+			}
+		}
+
+		switch format {
+		case CaptureANSI:
+			line.WriteString("\x1b[0m")
+		case CaptureHTML:
+			if open {
+				line.WriteString("</span>")
+			}
+		default:
+			out.WriteString(strings.TrimRight(line.String(), " "))
+			out.WriteByte('\n')
+			continue
+		}
+		out.WriteString(line.String())
+		out.WriteByte('\n')
+	}
+
+	if format == CaptureHTML {
+		out.WriteString("</pre>\n</body>\n</html>\n")
+	}
+
+	return out.String()
+}
+
+// ansiStyle returns the escape sequence selecting the style
+func ansiStyle(style tcell.Style) string {
+	fg, bg, attr := style.Decompose()
+
+	codes := []string{"0"}
+	for _, a := range []struct {
+		mask tcell.AttrMask
+		code string
+	}{
+		{tcell.AttrBold, "1"},
+		{tcell.AttrDim, "2"},
+		{tcell.AttrItalic, "3"},
+		{tcell.AttrUnderline, "4"},
+		{tcell.AttrBlink, "5"},
+		{tcell.AttrReverse, "7"},
+		{tcell.AttrStrikeThrough, "9"},
+	} {
+		if attr&a.mask != 0 {
+			codes = append(codes, a.code)
+		}
+	}
+	if r, g, b := fg.RGB(); r >= 0 {
+		codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+	}
+	if r, g, b := bg.RGB(); r >= 0 {
+		codes = append(codes, fmt.Sprintf("48;2;%d;%d;%d", r, g, b))
+	}
+
+	return "\x1b[" + strings.Join(codes, ";") + "m"
+}
+
+// cssStyle returns the CSS declarations of the style
+func cssStyle(style tcell.Style) string {
+	fg, bg, attr := style.Decompose()
+	if attr&tcell.AttrReverse != 0 {
+		fg, bg = bg, fg
+		if fg == tcell.ColorDefault {
+			fg = tview.Styles.PrimitiveBackgroundColor
+		}
+		if bg == tcell.ColorDefault {
+			bg = tview.Styles.PrimaryTextColor
+		}
+	}
+
+	var css []string
+	if r, g, b := fg.RGB(); r >= 0 {
+		css = append(css, fmt.Sprintf("color: #%02x%02x%02x", r, g, b))
+	}
+	if r, g, b := bg.RGB(); r >= 0 {
+		css = append(css, fmt.Sprintf("background-color: #%02x%02x%02x", r, g, b))
+	}
+	if attr&tcell.AttrBold != 0 {
+		css = append(css, "font-weight: bold")
+	}
+	if attr&tcell.AttrDim != 0 {
+		css = append(css, "opacity: 0.6")
+	}
+	if attr&tcell.AttrItalic != 0 {
+		css = append(css, "font-style: italic")
+	}
+
+	var decorations []string
+	if attr&tcell.AttrUnderline != 0 {
+		decorations = append(decorations, "underline")
+	}
+	if attr&tcell.AttrStrikeThrough != 0 {
+		decorations = append(decorations, "line-through")
+	}
+	if attr&tcell.AttrBlink != 0 {
+		decorations = append(decorations, "blink")
+	}
+	if len(decorations) > 0 {
+		css = append(css, "text-decoration: "+strings.Join(decorations, " "))
+	}
+
+	return strings.Join(css, "; ")
+}
@@ -0,0 +1,109 @@
+package tilman
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestBufferCapture(t *testing.T) {
+	red := tcell.StyleDefault.Foreground(tcell.NewRGBColor(255, 0, 0)).Bold(true)
+	b := NewBuffer(4, 2)
+	b.SetContent(0, 0, 'a', nil, red)
+	b.SetContent(1, 0, '日', nil, tcell.StyleDefault)
+	b.SetContent(3, 0, '<', nil, tcell.StyleDefault)
+
+	if text := b.Capture(CaptureText); text != "a日<\n\n" {
+		t.Errorf("the text capture is %q", text)
+	}
+
+	want := "\x1b[0;1;38;2;255;0;0ma\x1b[0m日<\x1b[0m\n" +
+		"\x1b[0m    \x1b[0m\n"
+	if ansi := b.Capture(CaptureANSI); ansi != want {
+		t.Errorf("the ANSI capture is %q, want %q", ansi, want)
+	}
+
+	page := b.Capture(CaptureHTML)
+	for _, part := range []string{
+		"<!DOCTYPE html>",
+		`<span style="color: #ff0000; font-weight: bold">a</span>`,
+		"日&lt;",
+		"</html>",
+	} {
+		if !strings.Contains(page, part) {
+			t.Errorf("the HTML capture does not contain %q:\n%s", part, page)
+		}
+	}
+}
+
+func TestCaptureWindow(t *testing.T) {
+	m, windows := newPaneManager(true)
+	m.SetRect(0, 0, 80, 30)
+	screen := newSimulationScreen(t, 80, 30)
+	m.Draw(screen)
+	m.Draw(screen)
+
+	// the clean window draws all its cells into the capture, and on the
+	// screen again after it
+	w := windows[6]
+	if w.IsDirty() {
+		t.Fatal("the window is dirty after two draws")
+	}
+	if text := m.CaptureWindow(w, CaptureText); !strings.Contains(text, "pane 1.1") {
+		t.Errorf("the capture of the clean window is\n%s", text)
+	}
+	screen.Clear()
+	m.Invalidate().Draw(screen)
+	if !strings.Contains(screenText(screen), "pane 1.1") {
+		t.Error("the window is not drawn after the capture")
+	}
+
+	if text := m.Capture(CaptureText); !strings.Contains(text, "pane 0.0") || !strings.Contains(text, "pane 3.4") {
+		t.Errorf("the capture of the manager is\n%s", text)
+	}
+}
+
+func TestCaptureToFile(t *testing.T) {
+	dir := t.TempDir()
+	m := NewWindowManager().SetRoot(NewLayout().AddItemWeight(NewWindow().SetRoot(tview.NewBox()).SetTitle("captured"), 1))
+	m.SetRect(0, 0, 20, 5)
+
+	// the captures of the same second get their own files
+	var paths []string
+	for i := 0; i < 3; i++ {
+		path, err := m.CaptureToFile(dir, CaptureHTML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] == paths[1] || paths[1] == paths[2] || paths[0] == paths[2] {
+		t.Errorf("the captures were written to %q", paths)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(path) != ".html" || string(data) != m.Capture(CaptureHTML) {
+			t.Errorf("the file %s does not contain the capture", path)
+		}
+	}
+
+	if _, err := m.CaptureToFile(filepath.Join(dir, "missing"), CaptureText); err == nil {
+		t.Error("writing to a missing directory did not fail")
+	}
+
+	// with the capture action
+	actionDir := t.TempDir()
+	m.SetCaptureOptions(actionDir, CaptureText, func(err error) { t.Error(err) })
+	m.SetKeybinding(ActionCapture, KeyBinding{Key: tcell.KeyCtrlP})
+	m.InputHandler()(tcell.NewEventKey(tcell.KeyCtrlP, 0, tcell.ModNone), func(p tview.Primitive) {})
+	if files, err := os.ReadDir(actionDir); err != nil || len(files) != 1 {
+		t.Errorf("the capture action wrote %d files, %v", len(files), err)
+	}
+}
@@ -1,9 +1,17 @@
 package tilman
 
-import "github.com/gdamore/tcell/v2"
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+// DefaultPlaceholder is drawn instead of a wide character cut by the right edge
+// of a clip region
+var DefaultPlaceholder = ' '
 
 // ClipRegion implements tcell.Screen and only allows setting content within
-// a defined region
+// a defined region. A local region also translates the coordinates so that
+// (0,0) is its top-left corner.
 type ClipRegion struct {
 	tcell.Screen
 	x      int
@@ -11,30 +19,195 @@ type ClipRegion struct {
 	width  int
 	height int
 	style  tcell.Style
+	local  bool
+	// drawn instead of wide characters which do not fit into the region
+	placeholder rune
+	// the region intersected with the bounds of the wrapped screen, in the
+	// coordinates of the wrapped screen
+	clipX, clipY, clipWidth, clipHeight int
+}
+
+// bounded is a screen which only allows setting content within some bounds
+type bounded interface {
+	Bounds() (int, int, int, int)
 }
 
-// New creates a new clipped screen with the given rectangular coordinates
+// New creates a new clipped screen with the given rectangular coordinates. The
+// region is intersected with the bounds of the screen, if it is a clipped
+// screen itself, or with its size.
 func NewClipRegion(screen tcell.Screen, x, y, width, height int) *ClipRegion {
+	var parentX, parentY, parentWidth, parentHeight int
+	placeholder := DefaultPlaceholder
+	if parent, ok := screen.(*ClipRegion); ok {
+		placeholder = parent.placeholder
+	}
+	if parent, ok := screen.(bounded); ok {
+		parentX, parentY, parentWidth, parentHeight = parent.Bounds()
+	} else {
+		parentWidth, parentHeight = screen.Size()
+	}
+
+	clipX, clipY, clipWidth, clipHeight := intersect(x, y, width, height, parentX, parentY, parentWidth, parentHeight)
+
 	return &ClipRegion{
-		Screen: screen,
-		x:      x,
-		y:      y,
-		width:  width,
-		height: height,
-		style:  tcell.StyleDefault,
+		Screen:      screen,
+		x:           x,
+		y:           y,
+		width:       width,
+		height:      height,
+		style:       tcell.StyleDefault,
+		placeholder: placeholder,
+		clipX:       clipX,
+		clipY:       clipY,
+		clipWidth:   clipWidth,
+		clipHeight:  clipHeight,
+	}
+}
+
+// intersect returns the intersection of two rectangles, empty rectangles have
+// no width and height
+func intersect(x1, y1, width1, height1, x2, y2, width2, height2 int) (int, int, int, int) {
+	x, y := max(x1, x2), max(y1, y2)
+	right, bottom := min(x1+width1, x2+width2), min(y1+height1, y2+height2)
+	if right <= x || bottom <= y {
+		return x, y, 0, 0
+	}
+	return x, y, right - x, bottom - y
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
 	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// SetPlaceholder sets the character drawn instead of a wide character which is
+// cut by the right edge of the region, regions nested in it inherit it
+func (cr *ClipRegion) SetPlaceholder(placeholder rune) *ClipRegion {
+	cr.placeholder = placeholder
+	return cr
+}
+
+// GetPlaceholder returns the character drawn instead of a cut wide character
+func (cr *ClipRegion) GetPlaceholder() rune {
+	return cr.placeholder
+}
+
+// Bounds returns the area in which content can be set, in the coordinates of
+// the region. It is smaller than the region where the region exceeds the
+// bounds of the screen it wraps.
+func (cr *ClipRegion) Bounds() (int, int, int, int) {
+	x, y := cr.FromScreen(cr.clipX, cr.clipY)
+	return x, y, cr.clipWidth, cr.clipHeight
+}
+
+// NewLocalClipRegion creates a new clipped screen with the given rectangular
+// coordinates in which (0,0) is the top-left corner of the region, so that
+// primitives can draw without knowing where they are placed
+func NewLocalClipRegion(screen tcell.Screen, x, y, width, height int) *ClipRegion {
+	return NewClipRegion(screen, x, y, width, height).SetLocal(true)
+}
+
+// SetLocal sets whether the coordinates are relative to the top-left corner of
+// the region
+func (cr *ClipRegion) SetLocal(local bool) *ClipRegion {
+	cr.local = local
+	return cr
+}
+
+// IsLocal returns true if the coordinates are relative to the region
+func (cr *ClipRegion) IsLocal() bool {
+	return cr.local
+}
+
+// ToScreen converts coordinates of the region to coordinates of the
+// underlying screen
+func (cr *ClipRegion) ToScreen(x, y int) (int, int) {
+	if cr.local {
+		return x + cr.x, y + cr.y
+	}
+	return x, y
+}
+
+// FromScreen converts coordinates of the underlying screen to coordinates of
+// the region
+func (cr *ClipRegion) FromScreen(x, y int) (int, int) {
+	if cr.local {
+		return x - cr.x, y - cr.y
+	}
+	return x, y
+}
+
+// TranslateMouse returns the mouse event with the position converted to the
+// coordinates of the region. A translated event is a new event: tcell offers
+// no way to keep the time of the original one, When returns the time it was
+// translated.
+func (cr *ClipRegion) TranslateMouse(event *tcell.EventMouse) *tcell.EventMouse {
+	if !cr.local || event == nil {
+		return event
+	}
+
+	x, y := cr.FromScreen(event.Position())
+	return tcell.NewEventMouse(x, y, event.Buttons(), event.Modifiers())
 }
 
 // InRect returns true if the given coordinates are within this clipped region
+// and the regions it is nested in
 func (cr *ClipRegion) InRect(x, y int) bool {
-	return !(x < cr.x || y < cr.y || x >= cr.x+cr.width || y >= cr.y+cr.height)
+	x, y = cr.ToScreen(x, y)
+	return !(x < cr.clipX || y < cr.clipY || x >= cr.clipX+cr.clipWidth || y >= cr.clipY+cr.clipHeight)
+}
+
+// Size returns the size of the region if it is local, otherwise the extent of
+// the region on the screen, so that no coordinate beyond it is visible
+func (cr *ClipRegion) Size() (int, int) {
+	if cr.local {
+		return cr.width, cr.height
+	}
+	return cr.clipX + cr.clipWidth, cr.clipY + cr.clipHeight
 }
 
-// Fill implements tcell.Screen.Fill
+// GetContent returns the contents at the given location, a blank cell outside
+// the region
+func (cr *ClipRegion) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	if !cr.InRect(x, y) {
+		return ' ', nil, tcell.StyleDefault, 1
+	}
+
+	x, y = cr.ToScreen(x, y)
+	return cr.Screen.GetContent(x, y)
+}
+
+// SetStyle sets the style used to clear the region, the style of the screen
+// is left unchanged
+func (cr *ClipRegion) SetStyle(style tcell.Style) {
+	cr.style = style
+}
+
+// PollEvent waits for an event, mouse positions are converted to the
+// coordinates of the region
+func (cr *ClipRegion) PollEvent() tcell.Event {
+	event := cr.Screen.PollEvent()
+	if mouse, ok := event.(*tcell.EventMouse); ok {
+		return cr.TranslateMouse(mouse)
+	}
+	return event
+}
+
+// Fill implements tcell.Screen.Fill, it only fills the region
 func (cr *ClipRegion) Fill(ch rune, style tcell.Style) {
-	for x := cr.x; x < cr.width; x++ {
-		for y := cr.y; y < cr.height; y++ {
-			cr.SetContent(x, y, ch, nil, style)
+	x, y, width, height := cr.Bounds()
+	for x_ := x; x_ < x+width; x_++ {
+		for y_ := y; y_ < y+height; y_++ {
+			cr.SetContent(x_, y_, ch, nil, style)
 		}
 	}
 }
@@ -61,11 +234,19 @@ func (cr *ClipRegion) SetCell(x int, y int, style tcell.Style, ch ...rune) {
 // Note that wide (East Asian full width) runes occupy two cells,
 // and attempts to place character at next cell to the right will have
 // undefined effects.  Wide runes that are printed in the
-// last column will be replaced with a single width space on output.
+// last column of the region are replaced with the placeholder, together
+// with their combining characters, so they do not spill out of the region.
 func (cr *ClipRegion) SetContent(x int, y int, mainc rune, combc []rune, style tcell.Style) {
-	if cr.InRect(x, y) {
-		cr.Screen.SetContent(x, y, mainc, combc, style)
+	if !cr.InRect(x, y) {
+		return
 	}
+
+	if runewidth.RuneWidth(mainc) > 1 && !cr.InRect(x+1, y) {
+		mainc, combc = cr.placeholder, nil
+	}
+
+	x, y = cr.ToScreen(x, y)
+	cr.Screen.SetContent(x, y, mainc, combc, style)
 }
 
 // ShowCursor is used to display the cursor at a given location.
@@ -73,11 +254,12 @@ func (cr *ClipRegion) SetContent(x int, y int, mainc rune, combc []rune, style t
 // dimensions of the screen, the cursor will be hidden.
 func (cr *ClipRegion) ShowCursor(x int, y int) {
 	if cr.InRect(x, y) {
+		x, y = cr.ToScreen(x, y)
 		cr.Screen.ShowCursor(x, y)
 	}
 }
 
-// Clear clears the clipped region
+// Clear clears the clipped region with the style set by SetStyle
 func (cr *ClipRegion) Clear() {
 	cr.Fill(' ', cr.style)
 }
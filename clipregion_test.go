@@ -0,0 +1,247 @@
+package tilman
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// rect is a rectangle in screen coordinates
+type rect struct {
+	x, y, width, height int
+}
+
+func (r rect) contains(x, y int) bool {
+	return x >= r.x && y >= r.y && x < r.x+r.width && y < r.y+r.height
+}
+
+// randomRegions nests up to four random regions in the screen, some of them
+// local and some drawn through a style screen. It returns the innermost
+// region, the intersection of all of them with the screen and the origin of
+// the coordinates of the innermost region, in screen coordinates.
+func randomRegions(rnd *rand.Rand, screen tcell.Screen) (*ClipRegion, rect, int, int) {
+	width, height := screen.Size()
+	visible := rect{0, 0, width, height}
+
+	var region *ClipRegion
+	parent := screen
+	// the screen position of the coordinate origin of the parent
+	originX, originY := 0, 0
+
+	for depth := rnd.Intn(4) + 1; depth > 0; depth-- {
+		x, y := rnd.Intn(width+10)-5, rnd.Intn(height+10)-5
+		w, h := rnd.Intn(width+5), rnd.Intn(height+5)
+
+		if rnd.Intn(2) == 0 {
+			region = NewLocalClipRegion(parent, x, y, w, h)
+		} else {
+			region = NewClipRegion(parent, x, y, w, h)
+		}
+
+		screenX, screenY := originX+x, originY+y
+		visible.x, visible.y, visible.width, visible.height = intersect(
+			visible.x, visible.y, visible.width, visible.height, screenX, screenY, w, h)
+
+		if region.IsLocal() {
+			originX, originY = screenX, screenY
+		}
+		parent = region
+		if rnd.Intn(3) == 0 {
+			parent = NewStyleScreen(region, func(style tcell.Style) tcell.Style { return style })
+		}
+	}
+
+	return region, visible, originX, originY
+}
+
+// checkScreen fails the test if the cells set to '#' are not exactly the
+// visible rectangle
+func checkScreen(t *testing.T, screen tcell.SimulationScreen, visible rect, what string) {
+	t.Helper()
+
+	screen.Show()
+	cells, width, height := screen.GetContents()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			set := len(cells[y*width+x].Runes) > 0 && cells[y*width+x].Runes[0] == '#'
+			if set && !visible.contains(x, y) {
+				t.Fatalf("%s escaped the intersection %+v at (%d,%d)", what, visible, x, y)
+			}
+			if !set && visible.contains(x, y) {
+				t.Fatalf("%s did not reach (%d,%d) inside the intersection %+v", what, x, y, visible)
+			}
+		}
+	}
+}
+
+func TestClipRegionNestedIntersection(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	screen := newSimulationScreen(t, 30, 12)
+
+	for i := 0; i < 500; i++ {
+		region, visible, _, _ := randomRegions(rnd, screen)
+
+		screen.Fill('.', tcell.StyleDefault)
+		for y := -20; y < 40; y++ {
+			for x := -20; x < 60; x++ {
+				region.SetContent(x, y, '#', nil, tcell.StyleDefault)
+			}
+		}
+		checkScreen(t, screen, visible, "SetContent")
+
+		screen.Fill('.', tcell.StyleDefault)
+		region.Fill('#', tcell.StyleDefault)
+		checkScreen(t, screen, visible, "Fill")
+	}
+}
+
+func TestClipRegionNestedInRect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	screen := newSimulationScreen(t, 30, 12)
+
+	for i := 0; i < 500; i++ {
+		region, visible, originX, originY := randomRegions(rnd, screen)
+
+		for y := -20; y < 40; y++ {
+			for x := -20; x < 60; x++ {
+				if region.InRect(x, y) != visible.contains(originX+x, originY+y) {
+					t.Fatalf("InRect(%d,%d) = %v with the intersection %+v", x, y, region.InRect(x, y), visible)
+				}
+			}
+		}
+	}
+}
+
+func TestClipRegionWideCharacters(t *testing.T) {
+	const (
+		family = "\U0001F468\u200d\U0001F469\u200d\U0001F467" // man, woman, girl joined by ZWJ
+		accent = "e\u0301"                                    // e with a combining acute accent
+	)
+
+	tests := []struct {
+		name  string
+		local bool
+		draw  func(screen tcell.Screen)
+		want  map[int]string // the runes of the screen cells to check
+	}{
+		{
+			name: "wide rune inside",
+			draw: func(s tcell.Screen) { s.SetContent(3, 0, '世', nil, tcell.StyleDefault) },
+			want: map[int]string{3: "世"},
+		},
+		{
+			name: "wide rune at the right edge",
+			draw: func(s tcell.Screen) { s.SetContent(5, 0, '世', nil, tcell.StyleDefault) },
+			want: map[int]string{5: ">", 6: "."},
+		},
+		{
+			name: "wide rune outside",
+			draw: func(s tcell.Screen) { s.SetContent(6, 0, '世', nil, tcell.StyleDefault) },
+			want: map[int]string{6: ".", 7: "."},
+		},
+		{
+			name:  "wide rune at the right edge of a local region",
+			local: true,
+			draw:  func(s tcell.Screen) { s.SetContent(3, 0, '世', nil, tcell.StyleDefault) },
+			want:  map[int]string{5: ">", 6: "."},
+		},
+		{
+			name: "emoji at the right edge",
+			draw: func(s tcell.Screen) { s.SetContent(5, 0, '😀', nil, tcell.StyleDefault) },
+			want: map[int]string{5: ">", 6: "."},
+		},
+		{
+			name: "ZWJ emoji inside",
+			draw: func(s tcell.Screen) { tview.Print(s, family, 3, 0, 3, tview.AlignLeft, tcell.ColorWhite) },
+			want: map[int]string{3: family},
+		},
+		{
+			name: "ZWJ emoji at the right edge",
+			draw: func(s tcell.Screen) { tview.Print(s, family, 5, 0, 3, tview.AlignLeft, tcell.ColorWhite) },
+			want: map[int]string{5: ">", 6: "."},
+		},
+		{
+			name: "combining mark at the right edge",
+			draw: func(s tcell.Screen) { tview.Print(s, accent, 5, 0, 3, tview.AlignLeft, tcell.ColorWhite) },
+			want: map[int]string{5: accent, 6: "."},
+		},
+		{
+			name: "wide rune with a combining mark at the right edge",
+			draw: func(s tcell.Screen) { s.SetContent(5, 0, '世', []rune{'\u0301'}, tcell.StyleDefault) },
+			want: map[int]string{5: ">", 6: "."},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			screen := newSimulationScreen(t, 10, 1)
+			screen.Fill('.', tcell.StyleDefault)
+
+			// the region covers the columns 2 to 5
+			region := NewClipRegion(screen, 2, 0, 4, 1).SetPlaceholder('>')
+			if test.local {
+				region.SetLocal(true)
+			}
+			test.draw(region)
+
+			screen.Show()
+			cells, _, _ := screen.GetContents()
+			for x, want := range test.want {
+				if got := string(cells[x].Runes); got != want {
+					t.Errorf("cell %d is %q, want %q", x, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLocalClipRegion(t *testing.T) {
+	screen := newSimulationScreen(t, 20, 10)
+	region := NewLocalClipRegion(screen, 3, 2, 5, 4)
+
+	if width, height := region.Size(); width != 5 || height != 4 {
+		t.Errorf("the size is %dx%d, want 5x4", width, height)
+	}
+	if x, y := region.ToScreen(1, 2); x != 4 || y != 4 {
+		t.Errorf("(1,2) is (%d,%d) on the screen, want (4,4)", x, y)
+	}
+	if x, y := region.FromScreen(4, 4); x != 1 || y != 2 {
+		t.Errorf("(4,4) of the screen is (%d,%d), want (1,2)", x, y)
+	}
+
+	region.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	region.SetContent(4, 3, 'b', nil, tcell.StyleDefault)
+	region.SetContent(5, 0, 'c', nil, tcell.StyleDefault)
+	region.SetContent(-1, 0, 'd', nil, tcell.StyleDefault)
+	checkCells(t, screen, "local region", map[[2]int]rune{
+		{3, 2}: 'a',
+		{7, 5}: 'b',
+		{8, 2}: ' ',
+		{2, 2}: ' ',
+	})
+	if r, _, _, _ := region.GetContent(4, 3); r != 'b' {
+		t.Errorf("the content at (4,3) is %q, want 'b'", r)
+	}
+
+	region.ShowCursor(1, 1)
+	if x, y, visible := screen.GetCursor(); x != 4 || y != 3 || !visible {
+		t.Errorf("the cursor is at (%d,%d), want (4,3)", x, y)
+	}
+
+	event := region.TranslateMouse(tcell.NewEventMouse(4, 3, tcell.Button1, tcell.ModAlt))
+	if x, y := event.Position(); x != 1 || y != 1 || event.Buttons() != tcell.Button1 || event.Modifiers() != tcell.ModAlt {
+		t.Errorf("the translated event is at (%d,%d) with %v and %v", x, y, event.Buttons(), event.Modifiers())
+	}
+
+	// a region which is not local keeps the screen coordinates
+	region.SetLocal(false)
+	original := tcell.NewEventMouse(4, 3, tcell.Button1, tcell.ModNone)
+	if region.TranslateMouse(original) != original {
+		t.Error("the region which is not local translated the event")
+	}
+	if x, y := region.ToScreen(1, 2); x != 1 || y != 2 {
+		t.Errorf("(1,2) is (%d,%d) on the screen, want (1,2)", x, y)
+	}
+}
@@ -0,0 +1,179 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// directions a border line leaves a cell to
+const (
+	lineUp = 1 << iota
+	lineDown
+	lineLeft
+	lineRight
+)
+
+// junction returns the glyph joining the lines meeting in a cell from the
+// given directions, false for straight lines
+func junction(borders *BorderSet, directions int) (rune, bool) {
+	switch directions {
+	case lineUp | lineDown | lineLeft | lineRight:
+		return borders.Cross, true
+	case lineDown | lineLeft | lineRight:
+		return borders.TopT, true
+	case lineUp | lineLeft | lineRight:
+		return borders.BottomT, true
+	case lineUp | lineDown | lineRight:
+		return borders.LeftT, true
+	case lineUp | lineDown | lineLeft:
+		return borders.RightT, true
+	case lineDown | lineRight:
+		return borders.TopLeft, true
+	case lineDown | lineLeft:
+		return borders.TopRight, true
+	case lineUp | lineRight:
+		return borders.BottomLeft, true
+	case lineUp | lineLeft:
+		return borders.BottomRight, true
+	}
+	return 0, false
+}
+
+// segment is a horizontal or vertical border line between two points
+type segment struct {
+	x0, y0, x1, y1 int
+}
+
+// isCollapsed returns true if the layout draws collapsed borders
+func (l *Layout) isCollapsed() bool {
+	return l.collapsed || l.inheritedCollapsed
+}
+
+// placeItem returns the rectangle of an item occupying the given cell. Items
+// of a collapsed layout overlap the lines around their cell: nested layouts
+// draw their frame on them and bordered windows their title bar on the line
+// above.
+func (l *Layout) placeItem(p tview.Primitive, x, y, width, height int) (int, int, int, int) {
+	collapsed := l.isCollapsed()
+
+	switch p := p.(type) {
+	case *Layout:
+		p.inheritedCollapsed = collapsed
+		if collapsed {
+			return x - 1, y - 1, width + 2, height + 2
+		}
+	case *Window:
+		p.collapsed = collapsed
+		if collapsed && p.border {
+			return x, y - 1, width, height + 1
+		}
+	}
+
+	return x, y, width, height
+}
+
+// drawFrame draws the lines around the items of a collapsed layout
+func (l *Layout) drawFrame(screen tcell.Screen, borders *BorderSet, style tcell.Style) {
+	x, y, width, height := l.GetRect()
+	if width < 2 || height < 2 {
+		return
+	}
+
+	for x_ := x; x_ < x+width; x_++ {
+		screen.SetContent(x_, y, borders.Horizontal, nil, style)
+		screen.SetContent(x_, y+height-1, borders.Horizontal, nil, style)
+	}
+	for y_ := y; y_ < y+height; y_++ {
+		screen.SetContent(x, y_, borders.Vertical, nil, style)
+		screen.SetContent(x+width-1, y_, borders.Vertical, nil, style)
+	}
+}
+
+// segments returns the frame and splitter lines of the collapsed layout and of
+// all the layouts nested in it
+func (l *Layout) segments() []segment {
+	x, y, width, height := l.GetRect()
+	if width < 2 || height < 2 {
+		return nil
+	}
+
+	right, bottom := x+width-1, y+height-1
+	segments := []segment{
+		{x, y, right, y},
+		{x, bottom, right, bottom},
+		{x, y, x, bottom},
+		{right, y, right, bottom},
+	}
+
+	// splitters reach the frame
+	for _, s := range l.splitters {
+		if s.vertical {
+			segments = append(segments, segment{s.line(), y, s.line(), bottom})
+		} else {
+			segments = append(segments, segment{x, s.line(), right, s.line()})
+		}
+	}
+
+	for _, item := range l.items {
+		if nested, ok := item.Primitive.(*Layout); ok {
+			segments = append(segments, nested.segments()...)
+		}
+	}
+
+	return segments
+}
+
+// drawJunctions draws the glyphs joining the lines where they cross or meet
+func (l *Layout) drawJunctions(screen tcell.Screen, borders *BorderSet, style tcell.Style) {
+	type cell struct{ x, y int }
+	lines := make(map[cell]int)
+
+	for _, s := range l.segments() {
+		if s.y0 == s.y1 {
+			for x := s.x0; x <= s.x1; x++ {
+				if x > s.x0 {
+					lines[cell{x, s.y0}] |= lineLeft
+				}
+				if x < s.x1 {
+					lines[cell{x, s.y0}] |= lineRight
+				}
+			}
+		} else {
+			for y := s.y0; y <= s.y1; y++ {
+				if y > s.y0 {
+					lines[cell{s.x0, y}] |= lineUp
+				}
+				if y < s.y1 {
+					lines[cell{s.x0, y}] |= lineDown
+				}
+			}
+		}
+	}
+
+	for c, directions := range lines {
+		// straight lines are left as drawn, e.g. with a window title on them
+		glyph, ok := junction(borders, directions)
+		if !ok {
+			continue
+		}
+
+		// do not overwrite titles and buttons crossing a junction
+		if mainc, _, _, _ := screen.GetContent(c.x, c.y); !isLineGlyph(borders, mainc) {
+			continue
+		}
+
+		screen.SetContent(c.x, c.y, glyph, nil, style)
+	}
+}
+
+// isLineGlyph returns true if the rune is a part of a border line
+func isLineGlyph(borders *BorderSet, r rune) bool {
+	switch r {
+	case ' ', borders.Horizontal, borders.Vertical,
+		borders.HorizontalFocus, borders.VerticalFocus:
+		return true
+	}
+
+	// box drawing block
+	return r >= 0x2500 && r <= 0x257f
+}
@@ -0,0 +1,69 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestCollapsedBorders(t *testing.T) {
+	window := func(title string) *Window {
+		return NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle(title)
+	}
+	row := func(left, right string) *Layout {
+		return NewLayout().SetDirection(HorizontalLayout).
+			AddItemWeight(window(left), 1).
+			AddItemWeight(window(right), 1)
+	}
+
+	tests := []struct {
+		name   string
+		layout *Layout
+		want   string
+	}{
+		{
+			name: "nested",
+			layout: NewLayout().SetDirection(HorizontalLayout).
+				AddItemWeight(window("A"), 1).
+				AddItemWeight(NewLayout().SetDirection(VerticalLayout).
+					AddItemWeight(window("B"), 1).
+					AddItemWeight(row("C", "D"), 1), 1),
+			want: "" +
+				"┌────A────┬────B────┐\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"│         ├─C──┬─D──┤\n" +
+				"│         │    │    │\n" +
+				"│         │    │    │\n" +
+				"│         │    │    │\n" +
+				"└─────────┴────┴────┘\n",
+		},
+		{
+			name: "grid",
+			layout: NewLayout().SetDirection(VerticalLayout).
+				AddItemWeight(row("A", "B"), 1).
+				AddItemWeight(row("C", "D"), 1),
+			want: "" +
+				"┌────A────┬────B────┐\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"├────C────┼────D────┤\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"│         │         │\n" +
+				"└─────────┴─────────┘\n",
+		},
+	}
+
+	for _, test := range tests {
+		test.layout.SetCollapsedBorders(true).SetRect(0, 0, 21, 9)
+		screen := newSimulationScreen(t, 21, 9)
+		test.layout.Draw(screen)
+
+		if got := screenText(screen); got != test.want {
+			t.Errorf("%s: the screen is\n%swant\n%s", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,594 @@
+package tilman
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ConfigDecoder decodes the contents of a configuration file into a tree of
+// maps with string keys, slices, strings, numbers and booleans
+type ConfigDecoder func(data []byte) (map[string]interface{}, error)
+
+var (
+	configFormatsMutex sync.Mutex
+	configFormats      = map[string]ConfigDecoder{
+		".json": func(data []byte) (map[string]interface{}, error) {
+			var tree map[string]interface{}
+			err := json.Unmarshal(data, &tree)
+			return tree, err
+		},
+		".toml": decodeTOML,
+		".yaml": decodeYAML,
+		".yml":  decodeYAML,
+	}
+)
+
+// RegisterConfigFormat registers the decoder for configuration files with the
+// given extension, e.g. ".ini", or replaces the decoder of a supported format.
+// JSON, TOML and YAML are supported out of the box, the TOML and YAML decoders
+// only read the subset of the formats needed by configurations (strings,
+// lists and tables) and may be replaced by a full parser.
+func RegisterConfigFormat(ext string, decoder ConfigDecoder) {
+	configFormatsMutex.Lock()
+	defer configFormatsMutex.Unlock()
+
+	configFormats[strings.ToLower(ext)] = decoder
+}
+
+// ConfigError describes an invalid value in a configuration
+type ConfigError struct {
+	File    string // the configuration file, if any
+	Key     string // the dotted path of the offending key, e.g. "splitter.color"
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	var parts []string
+	if e.File != "" {
+		parts = append(parts, e.File)
+	}
+	if e.Key != "" {
+		parts = append(parts, e.Key)
+	}
+	return strings.Join(append(parts, e.Message), ": ")
+}
+
+// Config holds the user configuration of a window manager. All settings are
+// optional, settings missing in the configuration are left unchanged. The
+// configuration is written in JSON, TOML or YAML (see RegisterConfigFormat).
+// A key may only be bound to one action, keys which match the same events,
+// such as "Alt+m" and "Alt+M", are the same key.
+//
+// A configuration looks like this (in JSON):
+//
+//   {
+//     "theme":    "solarized-dark",
+//     "keys":     {"focus-next": "Ctrl+N", "toggle-maximize": "Alt+M"},
+//     "splitter": {"color": "gray", "attributes": ["bold"]},
+//     "borders":  {"horizontal": "─", "horizontal-focus": "═"},
+//     "title":    {"color": "yellow", "align": "left"},
+//     "buttons":  {"close": "x", "maximize": "▴"},
+//     "layout":   "h|[ v[logs:30%, shell], editor:2* ]"
+//   }
+type Config struct {
+	theme              *Theme
+	keys               map[Action]KeyBinding
+	splitterColor      *tcell.Color
+	splitterAttributes *tcell.AttrMask
+	borders            map[string]rune
+	titleColor         *tcell.Color
+	titleAlign         *int
+	buttons            map[string]rune
+	layout             string
+}
+
+// configBorders maps the border glyph names to the glyphs of a border set
+var configBorders = map[string]func(set *BorderSet) *rune{
+	"horizontal":         func(set *BorderSet) *rune { return &set.Horizontal },
+	"vertical":           func(set *BorderSet) *rune { return &set.Vertical },
+	"top-left":           func(set *BorderSet) *rune { return &set.TopLeft },
+	"top-right":          func(set *BorderSet) *rune { return &set.TopRight },
+	"bottom-left":        func(set *BorderSet) *rune { return &set.BottomLeft },
+	"bottom-right":       func(set *BorderSet) *rune { return &set.BottomRight },
+	"left-t":             func(set *BorderSet) *rune { return &set.LeftT },
+	"right-t":            func(set *BorderSet) *rune { return &set.RightT },
+	"top-t":              func(set *BorderSet) *rune { return &set.TopT },
+	"bottom-t":           func(set *BorderSet) *rune { return &set.BottomT },
+	"cross":              func(set *BorderSet) *rune { return &set.Cross },
+	"horizontal-focus":   func(set *BorderSet) *rune { return &set.HorizontalFocus },
+	"vertical-focus":     func(set *BorderSet) *rune { return &set.VerticalFocus },
+	"top-left-focus":     func(set *BorderSet) *rune { return &set.TopLeftFocus },
+	"top-right-focus":    func(set *BorderSet) *rune { return &set.TopRightFocus },
+	"bottom-left-focus":  func(set *BorderSet) *rune { return &set.BottomLeftFocus },
+	"bottom-right-focus": func(set *BorderSet) *rune { return &set.BottomRightFocus },
+}
+
+var configAttributes = map[string]tcell.AttrMask{
+	"bold":      tcell.AttrBold,
+	"blink":     tcell.AttrBlink,
+	"reverse":   tcell.AttrReverse,
+	"underline": tcell.AttrUnderline,
+	"dim":       tcell.AttrDim,
+	"italic":    tcell.AttrItalic,
+}
+
+var configAligns = map[string]int{
+	"left":   tview.AlignLeft,
+	"center": tview.AlignCenter,
+	"right":  tview.AlignRight,
+}
+
+// LoadConfig reads the configuration file, its format is chosen by the file
+// extension
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := ParseConfig(data, filepath.Ext(path))
+	if err, ok := err.(*ConfigError); ok {
+		err.File = path
+	}
+
+	return config, err
+}
+
+// ParseConfig parses a configuration in the format registered for the given
+// extension
+func ParseConfig(data []byte, ext string) (*Config, error) {
+	configFormatsMutex.Lock()
+	decode, ok := configFormats[strings.ToLower(ext)]
+	configFormatsMutex.Unlock()
+
+	if !ok {
+		return nil, &ConfigError{Message: fmt.Sprintf("unsupported configuration format %q", ext)}
+	}
+
+	tree, err := decode(data)
+	if err != nil {
+		return nil, &ConfigError{Message: err.Error()}
+	}
+
+	config := &Config{}
+	if err := config.parse(tree); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func (c *Config) parse(tree map[string]interface{}) error {
+	for _, key := range sortedKeys(tree) {
+		value := tree[key]
+
+		var err error
+		switch key {
+		case "theme":
+			err = c.parseTheme(value)
+		case "keys":
+			err = c.parseKeys(value)
+		case "splitter":
+			err = c.parseSplitter(value)
+		case "borders":
+			c.borders, err = parseGlyphs(key, value, func(name string) bool {
+				return configBorders[name] != nil
+			})
+		case "title":
+			err = c.parseTitle(value)
+		case "buttons":
+			c.buttons, err = parseGlyphs(key, value, nil)
+		case "layout":
+			err = c.parseLayout(value)
+		default:
+			err = &ConfigError{Key: key, Message: "unknown key"}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) parseTheme(value interface{}) error {
+	name, err := configString("theme", value)
+	if err != nil {
+		return err
+	}
+
+	theme, ok := Themes[strings.ToLower(name)]
+	if !ok {
+		return &ConfigError{Key: "theme", Message: fmt.Sprintf("unknown theme %q", name)}
+	}
+
+	c.theme = theme()
+	return nil
+}
+
+func (c *Config) parseKeys(value interface{}) error {
+	keys, err := configTable("keys", value)
+	if err != nil {
+		return err
+	}
+
+	c.keys = make(map[Action]KeyBinding)
+	// the action each key is bound to, a key triggers a single action
+	bindings := make(map[KeyBinding]string)
+	for _, name := range sortedKeys(keys) {
+		path := "keys." + name
+
+		known := false
+		for _, action := range Actions {
+			known = known || string(action) == name
+		}
+		if !known {
+			return &ConfigError{Key: path, Message: "unknown action"}
+		}
+
+		text, err := configString(path, keys[name])
+		if err != nil {
+			return err
+		}
+
+		key, err := ParseKeyBinding(text)
+		if err != nil {
+			return &ConfigError{Key: path, Message: err.Error()}
+		}
+		if bound, ok := bindings[key.normalized()]; ok {
+			return &ConfigError{Key: path, Message: fmt.Sprintf("key %s is already bound to %s", key, bound)}
+		}
+		bindings[key.normalized()] = name
+		c.keys[Action(name)] = key
+	}
+
+	return nil
+}
+
+func (c *Config) parseSplitter(value interface{}) error {
+	splitter, err := configTable("splitter", value)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(splitter) {
+		path := "splitter." + name
+
+		switch name {
+		case "color":
+			color, err := configColor(path, splitter[name])
+			if err != nil {
+				return err
+			}
+			c.splitterColor = &color
+		case "attributes":
+			attributes, err := configAttributeMask(path, splitter[name])
+			if err != nil {
+				return err
+			}
+			c.splitterAttributes = &attributes
+		default:
+			return &ConfigError{Key: path, Message: "unknown key"}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) parseTitle(value interface{}) error {
+	title, err := configTable("title", value)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(title) {
+		path := "title." + name
+
+		switch name {
+		case "color":
+			color, err := configColor(path, title[name])
+			if err != nil {
+				return err
+			}
+			c.titleColor = &color
+		case "align":
+			text, err := configString(path, title[name])
+			if err != nil {
+				return err
+			}
+			align, ok := configAligns[strings.ToLower(text)]
+			if !ok {
+				return &ConfigError{Key: path, Message: fmt.Sprintf("unknown alignment %q, expected left, center or right", text)}
+			}
+			c.titleAlign = &align
+		default:
+			return &ConfigError{Key: path, Message: "unknown key"}
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) parseLayout(value interface{}) error {
+	text, err := configString("layout", value)
+	if err != nil {
+		return err
+	}
+
+	if _, err := parseLayout(text, nil, false); err != nil {
+		return &ConfigError{Key: "layout", Message: err.Error()}
+	}
+
+	c.layout = text
+	return nil
+}
+
+func parseGlyphs(path string, value interface{}, valid func(name string) bool) (map[string]rune, error) {
+	table, err := configTable(path, value)
+	if err != nil {
+		return nil, err
+	}
+
+	glyphs := make(map[string]rune)
+	for _, name := range sortedKeys(table) {
+		key := path + "." + name
+
+		if valid != nil && !valid(name) {
+			return nil, &ConfigError{Key: key, Message: "unknown key"}
+		}
+
+		text, err := configString(key, table[name])
+		if err != nil {
+			return nil, err
+		}
+		if utf8.RuneCountInString(text) != 1 {
+			return nil, &ConfigError{Key: key, Message: fmt.Sprintf("expected a single character, got %q", text)}
+		}
+
+		r, _ := utf8.DecodeRuneInString(text)
+		glyphs[name] = r
+	}
+
+	return glyphs, nil
+}
+
+func configTable(path string, value interface{}) (map[string]interface{}, error) {
+	table, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &ConfigError{Key: path, Message: fmt.Sprintf("expected a table, got %s", configType(value))}
+	}
+	return table, nil
+}
+
+func configString(path string, value interface{}) (string, error) {
+	text, ok := value.(string)
+	if !ok {
+		return "", &ConfigError{Key: path, Message: fmt.Sprintf("expected a string, got %s", configType(value))}
+	}
+	return text, nil
+}
+
+func configColor(path string, value interface{}) (tcell.Color, error) {
+	name, err := configString(path, value)
+	if err != nil {
+		return tcell.ColorDefault, err
+	}
+
+	name = strings.ToLower(name)
+	color := tcell.GetColor(name)
+	if color == tcell.ColorDefault && name != "default" {
+		return color, &ConfigError{Key: path, Message: fmt.Sprintf("unknown color %q", name)}
+	}
+	return color, nil
+}
+
+func configAttributeMask(path string, value interface{}) (tcell.AttrMask, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return 0, &ConfigError{Key: path, Message: fmt.Sprintf("expected a list, got %s", configType(value))}
+	}
+
+	var mask tcell.AttrMask
+	for i, item := range list {
+		key := fmt.Sprintf("%s[%d]", path, i)
+
+		name, err := configString(key, item)
+		if err != nil {
+			return 0, err
+		}
+
+		attribute, ok := configAttributes[strings.ToLower(name)]
+		if !ok {
+			return 0, &ConfigError{Key: key, Message: fmt.Sprintf("unknown attribute %q", name)}
+		}
+		mask |= attribute
+	}
+
+	return mask, nil
+}
+
+func configType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a table"
+	default:
+		return "a number"
+	}
+}
+
+func sortedKeys(table map[string]interface{}) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetKeybinding returns the key configured for the action, if any
+func (c *Config) GetKeybinding(action Action) (KeyBinding, bool) {
+	key, ok := c.keys[action]
+	return key, ok
+}
+
+// ButtonSymbol returns the configured symbol of the named window button, or
+// the given default if the button is not configured
+func (c *Config) ButtonSymbol(name string, def rune) rune {
+	if symbol, ok := c.buttons[name]; ok {
+		return symbol
+	}
+	return def
+}
+
+// DefaultLayout builds the configured layout (see ParseLayout) with the given
+// primitives bound to its slots. It returns nil if no layout is configured.
+func (c *Config) DefaultLayout(slots map[string]tview.Primitive) (*Layout, error) {
+	if c.layout == "" {
+		return nil, nil
+	}
+
+	layout, err := ParseLayout(c.layout, slots)
+	if err != nil {
+		return nil, &ConfigError{Key: "layout", Message: err.Error()}
+	}
+	return layout, nil
+}
+
+// Apply configures the manager and all the layouts and windows it contains.
+// The splitter and title colors change the theme of the manager, if it has
+// one. The border glyphs change the border set of the manager (see
+// Manager.SetBorderSet), other tview primitives keep theirs. It locks the
+// manager, see Manager.
+func (c *Config) Apply(m *Manager) {
+	m.Lock()
+	defer m.Unlock()
+
+	c.applyManager(m)
+}
+
+// applyManager configures the manager, the caller holds its lock
+func (c *Config) applyManager(m *Manager) {
+	theme := m.theme
+	if c.theme != nil {
+		theme = c.theme
+	}
+	if theme != nil {
+		configured := *theme
+		if c.splitterColor != nil {
+			configured.Splitter = configured.Splitter.Foreground(*c.splitterColor)
+			configured.SplitterFocused = configured.SplitterFocused.Foreground(*c.splitterColor)
+			configured.SplitterDragging = configured.SplitterDragging.Foreground(*c.splitterColor)
+		}
+		if c.splitterAttributes != nil {
+			configured.Splitter = configured.Splitter.Attributes(*c.splitterAttributes)
+			configured.SplitterFocused = configured.SplitterFocused.Attributes(*c.splitterAttributes)
+			configured.SplitterDragging = configured.SplitterDragging.Attributes(*c.splitterAttributes)
+		}
+		if c.titleColor != nil {
+			configured.Title = configured.Title.Foreground(*c.titleColor)
+			configured.TitleFocused = configured.TitleFocused.Foreground(*c.titleColor)
+		}
+		m.SetTheme(&configured)
+	}
+
+	for action, key := range c.keys {
+		m.SetKeybinding(action, key)
+	}
+
+	if len(c.borders) > 0 {
+		set := *orDefaultBorders(m.borders)
+		for name, glyph := range c.borders {
+			*configBorders[name](&set) = glyph
+		}
+		m.SetBorderSet(&set)
+	}
+
+	c.apply(m.logicalRoot)
+	if m.visibleRoot != m.logicalRoot {
+		c.apply(m.visibleRoot)
+	}
+}
+
+func (c *Config) apply(p tview.Primitive) {
+	switch p := p.(type) {
+	case *Layout:
+		if c.splitterColor != nil {
+			p.SetSplitterColor(*c.splitterColor)
+		}
+		if c.splitterAttributes != nil {
+			p.SetSplitterAttributes(*c.splitterAttributes)
+		}
+		for _, item := range p.items {
+			c.apply(item.Primitive)
+		}
+
+	case *Window:
+		if c.titleColor != nil {
+			p.titleColor = *c.titleColor
+		}
+		if c.titleAlign != nil {
+			p.titleAlign = *c.titleAlign
+		}
+		for _, button := range p.buttons {
+			if symbol, ok := c.buttons[button.Name]; ok && button.Name != "" {
+				button.Symbol = symbol
+			}
+		}
+	}
+}
+
+// WatchConfig polls the configuration file every interval and calls onChange
+// with the newly loaded configuration (or the error loading it) whenever the
+// file is modified. Applications usually apply the configuration with
+// Application.QueueUpdateDraw. Calling the returned function stops watching.
+func WatchConfig(path string, interval time.Duration, onChange func(config *Config, err error)) (stop func()) {
+	done := make(chan struct{})
+
+	var modified time.Time
+	if info, err := os.Stat(path); err == nil {
+		modified = info.ModTime()
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(path)
+				if err != nil || info.ModTime().Equal(modified) {
+					continue
+				}
+				modified = info.ModTime()
+				onChange(LoadConfig(path))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,171 @@
+package tilman
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseConfigFormats(t *testing.T) {
+	configs := map[string]string{
+		".json": `{
+  "keys":     {"focus-next": "Ctrl+N", "toggle-maximize": "Alt+M"},
+  "splitter": {"color": "gray", "attributes": ["bold", "underline"]},
+  "borders":  {"horizontal": "─", "horizontal-focus": "═"},
+  "title":    {"color": "yellow", "align": "left"},
+  "buttons":  {"close": "x", "maximize": "▴"},
+  "layout":   "h|[ v[logs:30%, shell], editor:2* ]"
+}`,
+		".toml": `# keys of the actions
+layout = "h|[ v[logs:30%, shell], editor:2* ]"
+keys = { focus-next = "Ctrl+N", "toggle-maximize" = 'Alt+M' }
+
+[splitter]
+color = "gray" # a comment
+attributes = [
+  "bold",
+  "underline", # trailing comma
+]
+
+[borders]
+horizontal = "─"
+horizontal-focus = "═"
+
+[title]
+color = "yellow"
+align = "left"
+
+[buttons]
+close = 'x'
+maximize = "▴"
+`,
+		".yaml": `---
+# keys of the actions
+keys: {focus-next: Ctrl+N, "toggle-maximize": 'Alt+M'}
+splitter:
+  color: gray # a comment
+  attributes:
+    - bold
+    - "underline"
+borders:
+  horizontal: "─"
+  horizontal-focus: ═
+title: {color: yellow, align: left}
+buttons:
+    close: x
+    maximize: "▴"
+layout: h|[ v[logs:30%, shell], editor:2* ]
+`,
+	}
+
+	want, err := ParseConfig([]byte(configs[".json"]), ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the extensions are case insensitive
+	configs[".YML"] = configs[".yaml"]
+	for _, ext := range []string{".toml", ".yaml", ".YML"} {
+		config, err := ParseConfig([]byte(configs[ext]), ext)
+		if err != nil {
+			t.Errorf("%s: %v", ext, err)
+			continue
+		}
+		if !reflect.DeepEqual(config, want) {
+			t.Errorf("%s: the configuration is %+v, want %+v", ext, config, want)
+		}
+	}
+}
+
+func TestParseConfigDuplicateKeys(t *testing.T) {
+	tests := []struct {
+		first, second string
+		duplicate     bool
+	}{
+		{"Alt+m", "Alt+M", true},
+		{"Ctrl+N", "Ctrl+n", true},
+		{"a", "Shift+A", true},
+		{"Tab", "Ctrl+I", true},
+		{"Alt+m", "Meta+m", false},
+		{"F2", "Shift+F2", false},
+		{"Ctrl+N", "Alt+N", false},
+	}
+
+	for _, test := range tests {
+		text := fmt.Sprintf(`{"keys": {"focus-next": %q, "toggle-maximize": %q}}`, test.first, test.second)
+		_, err := ParseConfig([]byte(text), ".json")
+		if test.duplicate && err == nil {
+			t.Errorf("%s and %s were both bound", test.first, test.second)
+		}
+		if !test.duplicate && err != nil {
+			t.Errorf("%s and %s: %v", test.first, test.second, err)
+		}
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		ext, text, message string
+	}{
+		{".json", `{"keys": {"jump": "F2"}}`, "keys.jump: unknown action"},
+		{".json", `{"splitter": {"color": "grey-ish"}}`, `splitter.color: unknown color "grey-ish"`},
+		{".json", `{"borders": {"horizontal": "=="}}`, "borders.horizontal: expected a single character"},
+		{".json", `{"layout": "h[a:0]"}`, "layout: line 1, column 5"},
+		{".ini", `keys=`, "unsupported configuration format"},
+		{".toml", "[keys]\nfocus-next = \"F2\"\n[keys]", "line 3: table keys is defined twice"},
+		{".toml", "title = \"\"\"\nx\"\"\"", "line 1: multi-line strings are not supported"},
+		{".toml", "[[buttons]]", "arrays of tables are not supported"},
+		{".toml", "layout = \"h[a]\" x", "line 1: unexpected 'x'"},
+		{".toml", "title = { align = left }", "line 1: invalid value \"left\""},
+		{".toml", "splitter.color = \"red\"\nsplitter.color = \"red\"", "line 2: key splitter.color is defined twice"},
+		{".yaml", "keys:\n  focus-next: F2\n    toggle-maximize: F3", "line 3: unexpected indentation"},
+		{".yaml", "keys:\n\tfocus-next: F2", "line 2: tabs are not allowed"},
+		{".yaml", "title: &anchor {}", "line 1: unsupported value"},
+		{".yaml", "- a\n- b", "expected a mapping, got a list"},
+		{".yaml", "title: {color: red", "line 1: expected '}'"},
+		{".yaml", "buttons:\n  - close: x", "line 2: mappings in sequence items are not supported"},
+		{".yaml", "title:\n  color: \"red", "line 2: unterminated string"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseConfig([]byte(test.text), test.ext)
+		if _, ok := err.(*ConfigError); !ok || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%q returned %v, want a configuration error containing %q", test.text, err, test.message)
+		}
+	}
+}
+
+func TestConfigApplyTheme(t *testing.T) {
+	config, err := ParseConfig([]byte(`{
+  "theme": "monochrome",
+  "splitter": {"color": "red", "attributes": ["bold", "italic"]},
+  "title": {"color": "yellow"}
+}`), ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewWindowManager()
+	config.Apply(m)
+
+	theme := m.GetTheme()
+	for name, style := range map[string]tcell.Style{
+		"splitter":          theme.Splitter,
+		"focused splitter":  theme.SplitterFocused,
+		"dragging splitter": theme.SplitterDragging,
+	} {
+		fg, _, attr := style.Decompose()
+		if fg != tcell.ColorRed || attr != tcell.AttrBold|tcell.AttrItalic {
+			t.Errorf("the %s has color %v and attributes %v, want red, bold and italic", name, fg, attr)
+		}
+	}
+	if fg, _, _ := theme.TitleFocused.Decompose(); fg != tcell.ColorYellow {
+		t.Errorf("the focused title has color %v, want yellow", fg)
+	}
+
+	if Themes["monochrome"]().Splitter == theme.Splitter {
+		t.Error("applying the configuration changed the built-in theme")
+	}
+}
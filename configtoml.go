@@ -0,0 +1,387 @@
+package tilman
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeTOML decodes the subset of TOML used by configurations: tables, dotted
+// keys, single line basic and literal strings, integers, floats, booleans,
+// arrays and inline tables. Numbers are decoded as float64, like JSON numbers.
+// Multi-line strings, dates and arrays of tables are not supported.
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("invalid UTF-8")
+	}
+
+	p := &tomlParser{
+		text:    []rune(string(data)),
+		line:    1,
+		defined: make(map[string]bool),
+	}
+
+	root := make(map[string]interface{})
+	table := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			p.next()
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+
+			keys, err := p.keys()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(']'); err != nil {
+				return nil, err
+			}
+
+			name := strings.Join(keys, ".")
+			if p.defined[name] {
+				return nil, p.errorf("table %s is defined twice", name)
+			}
+			p.defined[name] = true
+
+			if table, err = p.table(root, keys); err != nil {
+				return nil, err
+			}
+		} else if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if !p.eof() && p.peek() != '\n' && p.peek() != '#' && p.peek() != '\r' {
+			return nil, p.errorf("unexpected %s at the end of the line", p.describe())
+		}
+	}
+}
+
+type tomlParser struct {
+	text []rune
+	pos  int
+	line int
+
+	// the headers of the tables defined so far
+	defined map[string]bool
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *tomlParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *tomlParser) next() rune {
+	r := p.peek()
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+// skipSpace skips the spaces on the current line
+func (p *tomlParser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.next()
+	}
+}
+
+// skipBlank skips spaces, newlines and comments
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.next()
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) describe() string {
+	if p.eof() {
+		return "end of file"
+	}
+	return strconv.QuoteRune(p.peek())
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) expect(r rune) error {
+	p.skipSpace()
+	if p.peek() != r {
+		return p.errorf("expected %q, found %s", r, p.describe())
+	}
+	p.next()
+	return nil
+}
+
+// keys parses a dotted key
+func (p *tomlParser) keys() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+
+		var key string
+		switch r := p.peek(); {
+		case r == '"' || r == '\'':
+			var err error
+			if key, err = p.string(); err != nil {
+				return nil, err
+			}
+		case isTOMLBareKeyRune(r):
+			for isTOMLBareKeyRune(p.peek()) {
+				key += string(p.next())
+			}
+		default:
+			return nil, p.errorf("expected a key, found %s", p.describe())
+		}
+		keys = append(keys, key)
+
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func isTOMLBareKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
+
+// table returns the table at the dotted key, creating the missing tables
+func (p *tomlParser) table(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		switch value := table[key].(type) {
+		case nil:
+			nested := make(map[string]interface{})
+			table[key] = nested
+			table = nested
+		case map[string]interface{}:
+			table = value
+		default:
+			return nil, p.errorf("key %s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+// keyValue parses a key, its value and adds them to the table
+func (p *tomlParser) keyValue(table map[string]interface{}) error {
+	keys, err := p.keys()
+	if err != nil {
+		return err
+	}
+	if err := p.expect('='); err != nil {
+		return err
+	}
+	p.skipSpace()
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	if table, err = p.table(table, keys[:len(keys)-1]); err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	if _, ok := table[key]; ok {
+		return p.errorf("key %s is defined twice", strings.Join(keys, "."))
+	}
+	table[key] = value
+
+	return nil
+}
+
+func (p *tomlParser) value() (interface{}, error) {
+	switch r := p.peek(); r {
+	case '"', '\'':
+		return p.string()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+
+	var word strings.Builder
+	for r := p.peek(); isTOMLBareKeyRune(r) || r == '+' || r == '.'; r = p.peek() {
+		word.WriteRune(p.next())
+	}
+
+	switch text := word.String(); text {
+	case "":
+		return nil, p.errorf("expected a value, found %s", p.describe())
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf", "-inf", "nan", "+nan", "-nan":
+		f, _ := strconv.ParseFloat(text, 64)
+		return f, nil
+	default:
+		return p.number(text)
+	}
+}
+
+// number parses an integer or a float, with optional underscores between the
+// digits, integers may be hexadecimal, octal or binary
+func (p *tomlParser) number(text string) (interface{}, error) {
+	digits := strings.ReplaceAll(text, "_", "")
+	unsigned := strings.TrimLeft(digits, "+-")
+
+	// leading zeros are not allowed
+	if len(unsigned) > 1 && unsigned[0] == '0' && unsigned[1] >= '0' && unsigned[1] <= '9' {
+		return nil, p.errorf("invalid number %q", text)
+	}
+
+	if i, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		return float64(i), nil
+	}
+	if strings.Trim(unsigned, "0123456789.eE+-") == "" {
+		if f, err := strconv.ParseFloat(digits, 64); err == nil {
+			return f, nil
+		}
+	}
+	return nil, p.errorf("invalid value %q", text)
+}
+
+// string parses a single line basic or literal string
+func (p *tomlParser) string() (string, error) {
+	quote := p.next()
+	if p.peek() == quote && p.pos+1 < len(p.text) && p.text[p.pos+1] == quote {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+
+	var b strings.Builder
+	for {
+		r := p.next()
+		switch {
+		case r == 0 && p.pos > len(p.text), r == '\n':
+			return "", p.errorf("unterminated string")
+		case r == quote:
+			return b.String(), nil
+		case r == '\\' && quote == '"':
+			escaped, err := p.escape()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(escaped)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// escape parses the escape sequence after a backslash
+func (p *tomlParser) escape() (rune, error) {
+	r := p.next()
+	switch r {
+	case 'b':
+		return '\b', nil
+	case 't':
+		return '\t', nil
+	case 'n':
+		return '\n', nil
+	case 'f':
+		return '\f', nil
+	case 'r':
+		return '\r', nil
+	case '"', '\\':
+		return r, nil
+	case 'u', 'U':
+		length := 4
+		if r == 'U' {
+			length = 8
+		}
+		if p.pos+length > len(p.text) {
+			return 0, p.errorf("invalid escape sequence")
+		}
+		code, err := strconv.ParseUint(string(p.text[p.pos:p.pos+length]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, p.errorf("invalid escape sequence")
+		}
+		p.pos += length
+		return rune(code), nil
+	default:
+		return 0, p.errorf("invalid escape sequence \\%c", r)
+	}
+}
+
+// array parses an array, which may span several lines
+func (p *tomlParser) array() ([]interface{}, error) {
+	p.next()
+
+	array := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.next()
+			return array, nil
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']', found %s", p.describe())
+		}
+	}
+}
+
+// inlineTable parses an inline table, which ends on the same line
+func (p *tomlParser) inlineTable() (map[string]interface{}, error) {
+	p.next()
+
+	table := make(map[string]interface{})
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.next()
+		return table, nil
+	}
+
+	for {
+		if err := p.keyValue(table); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case '}':
+			p.next()
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}', found %s", p.describe())
+		}
+	}
+}
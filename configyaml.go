@@ -0,0 +1,408 @@
+package tilman
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeYAML decodes the subset of YAML used by configurations: block
+// mappings and sequences, flow mappings and sequences, plain, single and
+// double quoted scalars and comments. Plain scalars are booleans, null,
+// numbers (decoded as float64, like JSON numbers) or strings. Anchors, tags,
+// block scalars and multiple documents are not supported.
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("invalid UTF-8")
+	}
+
+	p := &yamlParser{}
+	for i, text := range strings.Split(string(data), "\n") {
+		line, err := newYAMLLine(i+1, text)
+		if err != nil {
+			return nil, err
+		}
+		if line.text == "" || i == 0 && line.text == "---" {
+			continue
+		}
+		p.lines = append(p.lines, line)
+	}
+
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.lines[p.pos].errorf("unexpected indentation")
+	}
+
+	tree, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a mapping, got %s", configType(value))
+	}
+	return tree, nil
+}
+
+// yamlLine is a line without its indentation and comment
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+func newYAMLLine(number int, text string) (yamlLine, error) {
+	line := yamlLine{number: number}
+
+	text = strings.TrimRight(text, "\r")
+	trimmed := strings.TrimLeft(text, " ")
+	line.indent = len(text) - len(trimmed)
+	if strings.HasPrefix(trimmed, "\t") {
+		return line, line.errorf("tabs are not allowed in the indentation")
+	}
+
+	// a comment starts with '#' at the start of the line or after a space,
+	// outside of quotes
+	end := len(trimmed)
+	var quote byte
+	for i := 0; i < len(trimmed) && end == len(trimmed); i++ {
+		switch c := trimmed[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" \t[{,", trimmed[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || trimmed[i-1] == ' ' || trimmed[i-1] == '\t'):
+			end = i
+		}
+	}
+
+	line.text = strings.TrimRight(trimmed[:end], " \t")
+	return line, nil
+}
+
+func (l yamlLine) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.number, fmt.Sprintf(format, args...))
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// block parses the mapping or sequence of the lines with the given indentation
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if line := p.lines[p.pos]; line.text == "-" || strings.HasPrefix(line.text, "- ") {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	mapping := make(map[string]interface{})
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		p.pos++
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, line.errorf("expected a key followed by ':'")
+		}
+		name, err := yamlScalar(key)
+		if err != nil {
+			return nil, line.errorf("%v", err)
+		}
+		// only strings are used as keys, other scalars are kept as written
+		keyText, ok := name.(string)
+		if !ok {
+			keyText = key
+		}
+		if _, ok := mapping[keyText]; ok {
+			return nil, line.errorf("key %s is defined twice", keyText)
+		}
+
+		if mapping[keyText], err = p.value(line, rest, indent); err != nil {
+			return nil, err
+		}
+	}
+
+	return mapping, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	sequence := []interface{}{}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		if line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			return nil, line.errorf("expected a sequence item")
+		}
+		p.pos++
+
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if _, _, ok := splitYAMLKey(rest); ok && rest[0] != '{' && rest[0] != '[' {
+			return nil, line.errorf("mappings in sequence items are not supported")
+		}
+
+		value, err := p.value(line, rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+	}
+
+	return sequence, nil
+}
+
+// value parses the value following a key or a sequence item dash on the line,
+// or the nested block on the next lines if there is none
+func (p *yamlParser) value(line yamlLine, text string, indent int) (interface{}, error) {
+	if text != "" {
+		value, err := yamlValue(text)
+		if err != nil {
+			return nil, line.errorf("%v", err)
+		}
+		return value, nil
+	}
+
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	return nil, nil
+}
+
+// splitYAMLKey splits "key: value" into the key and the value, the colon must
+// be followed by a space or end the text
+func splitYAMLKey(text string) (string, string, bool) {
+	var quote rune
+	for i, r := range text {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case (r == '"' || r == '\'') && i == 0:
+			quote = r
+		case r == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+// yamlValue parses the value written on the line of its key or sequence item,
+// plain scalars may contain the characters ending them in flow collections
+func yamlValue(text string) (interface{}, error) {
+	switch text[0] {
+	case '[', '{':
+	case '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, fmt.Errorf("unsupported value %q", text)
+	default:
+		return yamlScalar(text)
+	}
+
+	p := &yamlFlowParser{text: text}
+
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q after the value", p.text[p.pos:])
+	}
+	return value, nil
+}
+
+type yamlFlowParser struct {
+	text string
+	pos  int
+}
+
+func (p *yamlFlowParser) skipSpace() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *yamlFlowParser) value() (interface{}, error) {
+	p.skipSpace()
+	if p.pos == len(p.text) {
+		return nil, nil
+	}
+
+	switch p.text[p.pos] {
+	case '[':
+		return p.sequence()
+	case '{':
+		return p.mapping()
+	case '&', '*', '!', '|', '>', '%', '@', '`':
+		return nil, fmt.Errorf("unsupported value %q", p.text[p.pos:])
+	}
+
+	return yamlScalar(p.scalarText())
+}
+
+// scalarText returns the text of the scalar at the current position, inside
+// of a flow collection a plain scalar ends at ',', ']', '}' or ": "
+func (p *yamlFlowParser) scalarText() string {
+	start := p.pos
+	if quote := p.text[p.pos]; quote == '"' || quote == '\'' {
+		for p.pos++; p.pos < len(p.text); p.pos++ {
+			switch {
+			case p.text[p.pos] == '\\' && quote == '"':
+				p.pos++
+			case p.text[p.pos] == quote:
+				if quote == '\'' && p.pos+1 < len(p.text) && p.text[p.pos+1] == '\'' {
+					p.pos++
+					continue
+				}
+				p.pos++
+				return p.text[start:p.pos]
+			}
+		}
+		return p.text[start:]
+	}
+
+	for ; p.pos < len(p.text); p.pos++ {
+		switch r := p.text[p.pos]; {
+		case r == ',' || r == ']' || r == '}':
+			return strings.TrimSpace(p.text[start:p.pos])
+		case r == ':' && (p.pos+1 == len(p.text) || p.text[p.pos+1] == ' '):
+			return strings.TrimSpace(p.text[start:p.pos])
+		}
+	}
+	return strings.TrimSpace(p.text[start:])
+}
+
+func (p *yamlFlowParser) expectEnd(end byte) (bool, error) {
+	p.skipSpace()
+	if p.pos == len(p.text) {
+		return false, fmt.Errorf("expected %q", end)
+	}
+	switch p.text[p.pos] {
+	case end:
+		p.pos++
+		return true, nil
+	case ',':
+		p.pos++
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected ',' or %q, found %q", end, p.text[p.pos:])
+	}
+}
+
+func (p *yamlFlowParser) sequence() ([]interface{}, error) {
+	p.pos++
+
+	sequence := []interface{}{}
+	if p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == ']' {
+		p.pos++
+		return sequence, nil
+	}
+
+	for {
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+
+		if end, err := p.expectEnd(']'); end || err != nil {
+			return sequence, err
+		}
+	}
+}
+
+func (p *yamlFlowParser) mapping() (map[string]interface{}, error) {
+	p.pos++
+
+	mapping := make(map[string]interface{})
+	if p.skipSpace(); p.pos < len(p.text) && p.text[p.pos] == '}' {
+		p.pos++
+		return mapping, nil
+	}
+
+	for {
+		p.skipSpace()
+		key, err := yamlScalar(p.scalarText())
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprint(key)
+
+		if p.skipSpace(); p.pos == len(p.text) || p.text[p.pos] != ':' {
+			return nil, fmt.Errorf("expected ':' after the key %s", name)
+		}
+		p.pos++
+
+		if mapping[name], err = p.value(); err != nil {
+			return nil, err
+		}
+
+		if end, err := p.expectEnd('}'); end || err != nil {
+			return mapping, err
+		}
+	}
+}
+
+// yamlScalar parses a quoted or plain scalar
+func yamlScalar(text string) (interface{}, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '"':
+		if len(text) < 2 || text[len(text)-1] != '"' {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", text)
+		}
+		return value, nil
+	case '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1), nil
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1), nil
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), nil
+	}
+
+	if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0o") {
+		if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+			return float64(i), nil
+		}
+	} else if strings.Trim(text, "0123456789+-.eE") == "" {
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+	}
+
+	return text, nil
+}
@@ -0,0 +1,339 @@
+package tilman
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// drawState is what a window looks like, a cached window is redrawn when it
+// changes. The content of the root primitive is not part of it, windows are
+// marked dirty when it changes.
+type drawState struct {
+	x, y, width, height int
+
+	root                tview.Primitive
+	focused, titleFocus bool
+	hovered, pressed    *WindowButton
+	tooltip             bool
+	dimmed, urgent      bool
+
+	title, buttons string
+	footer         [3]string
+	footerShown    bool
+
+	border, collapsed     bool
+	borderStyle           *BorderStyle
+	borders               *BorderSet
+	theme, inheritedTheme *Theme
+
+	scrollX, scrollY            int
+	virtualWidth, virtualHeight int
+}
+
+// state returns the current draw state of the window
+func (w *Window) state() drawState {
+	x, y, width, height := w.GetRect()
+
+	var buttons strings.Builder
+	for _, button := range w.buttons {
+		if w.buttonShown(button) {
+			buttons.WriteString(w.buttonText(button))
+		}
+		if button.Disabled {
+			buttons.WriteByte('-')
+		}
+	}
+
+	return drawState{
+		x:              x,
+		y:              y,
+		width:          width,
+		height:         height,
+		root:           w.root,
+		focused:        w.Box.HasFocus(),
+		titleFocus:     w.selected != nil,
+		hovered:        w.hovered,
+		pressed:        w.pressed,
+		tooltip:        w.tooltipShown(),
+		dimmed:         w.dimTransform != nil,
+		urgent:         w.urgent,
+		title:          w.GetTitle(),
+		buttons:        buttons.String(),
+		footer:         w.footer,
+		footerShown:    w.footerShown,
+		border:         w.border,
+		collapsed:      w.collapsed,
+		borderStyle:    w.borderStyle,
+		borders:        w.inheritedBorders,
+		theme:          w.theme,
+		inheritedTheme: w.inheritedTheme,
+		scrollX:        w.scrollX,
+		scrollY:        w.scrollY,
+		virtualWidth:   w.virtualWidth,
+		virtualHeight:  w.virtualHeight,
+	}
+}
+
+// MarkDirty makes the window redraw its content the next time it is drawn
+// instead of using its cache, e.g. after the text of its root changed. Only
+// needed with damage tracking, key and mouse events mark the window dirty.
+// It does not lock the manager: from another goroutine change the content and
+// mark the window inside Manager.Update.
+func (w *Window) MarkDirty() *Window {
+	w.dirty = true
+	return w
+}
+
+// IsDirty returns true if the window redraws its content the next time it is
+// drawn
+func (w *Window) IsDirty() bool {
+	return w.dirty || w.cache == nil || w.state() != w.cachedState
+}
+
+// drawCached redraws the window into its cache if it is dirty and copies the
+// cache onto the screen. A clean window is left as it is on the screen, unless
+// its parent was drawn over it.
+func (w *Window) drawCached(screen tcell.Screen) {
+	x, y, width, height := w.GetRect()
+
+	dirty := w.IsDirty()
+	if dirty {
+		if w.cache == nil {
+			w.cache = NewBuffer(width, height)
+		} else {
+			w.cache.SetSize(width, height)
+		}
+
+		// the window draws in screen coordinates, the cache starts at (0,0)
+		w.draw(NewLocalClipRegion(w.cache, -x, -y, x+width, y+height))
+		w.cachedState = w.state()
+		w.dirty = false
+	}
+
+	if dirty || w.exposed {
+		w.cache.Composite(screen, x, y, CompositeOpaque, 1)
+	} else if w.cache.cursorShown {
+		screen.ShowCursor(x+w.cache.cursorX, y+w.cache.cursorY)
+	}
+	w.exposed = false
+}
+
+// expose sets whether the layout or window was drawn over since its last draw,
+// which makes it draw all its cells again
+func expose(p tview.Primitive, exposed bool) {
+	switch p := p.(type) {
+	case *Layout:
+		p.exposed = exposed
+	case *Window:
+		p.exposed = exposed
+	}
+}
+
+// layoutState is what a layout draws besides its items, its background and
+// splitters are drawn again when it changes
+type layoutState struct {
+	x, y, width, height int
+
+	background                  tcell.Color
+	splitter, focused, dragging tcell.Style
+	borders                     *BorderSet
+
+	direction               Direction
+	collapsed, splitterFlag bool
+	focusedSplitter         int
+	dragged                 bool
+}
+
+// repaint returns true if the layout has to draw its background and splitters,
+// and its items all their cells, because it changed since the last draw or was
+// drawn over. It remembers the state and the sizes of the items.
+func (l *Layout) repaint(sizes []int) bool {
+	background, splitter, focused, dragging := l.splitterStyles()
+	state := layoutState{
+		background:      background,
+		splitter:        splitter,
+		focused:         focused,
+		dragging:        dragging,
+		borders:         l.inheritedBorders,
+		direction:       l.direction,
+		collapsed:       l.isCollapsed(),
+		splitterFlag:    l.splitterFlag,
+		focusedSplitter: l.focusedSplitterNumber,
+		dragged:         l.draggedSplitter != nil,
+	}
+	state.x, state.y, state.width, state.height = l.GetRect()
+
+	repaint := !l.damageTracking || l.exposed || state != l.drawnState || !equalSizes(sizes, l.drawnSizes)
+
+	// other primitives may draw without background, over the old cells
+	for _, item := range l.items {
+		switch item.Primitive.(type) {
+		case *Layout, *Window:
+		default:
+			repaint = true
+		}
+	}
+	// items overlap when the layout is too small, the last drawn is on top
+	for _, size := range sizes {
+		if size < 0 {
+			repaint = true
+		}
+	}
+	l.drawnState, l.drawnSizes, l.exposed = state, sizes, false
+
+	return repaint
+}
+
+func equalSizes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// frameState is what the manager draws around and under its windows, all of
+// them are drawn again when it changes
+type frameState struct {
+	screen                    tcell.Screen
+	screenWidth, screenHeight int
+
+	x, y, width, height                     int
+	innerX, innerY, innerWidth, innerHeight int
+	background, borderColor                 tcell.Color
+	borderAttributes                        tcell.AttrMask
+	title                                   string
+	focused                                 bool
+
+	visibleRoot  tview.Primitive
+	theme        *Theme
+	borders      *BorderSet
+	dimMode      DimMode
+	gap, padding int
+	singleWindow bool
+
+	// a menu or layers are drawn over the windows
+	overlay bool
+}
+
+// repaint returns true if the manager has to draw its background and all the
+// windows all their cells, because the frame changed since the last draw, a
+// menu or layers were drawn over the windows or the manager was invalidated.
+// It remembers the frame.
+func (m *Manager) repaint(screen tcell.Screen, singleWindow bool) bool {
+	frame := frameState{
+		screen:           screen,
+		background:       m.GetBackgroundColor(),
+		borderColor:      m.GetBorderColor(),
+		borderAttributes: m.GetBorderAttributes(),
+		title:            m.GetTitle(),
+		focused:          m.Box.HasFocus(),
+		visibleRoot:      m.visibleRoot,
+		theme:            m.theme,
+		borders:          m.borders,
+		dimMode:          m.dimMode,
+		gap:              m.gap,
+		padding:          m.padding,
+		singleWindow:     singleWindow,
+		overlay:          m.menu != nil || len(m.layers) > 0,
+	}
+	frame.screenWidth, frame.screenHeight = screen.Size()
+	frame.x, frame.y, frame.width, frame.height = m.GetRect()
+	frame.innerX, frame.innerY, frame.innerWidth, frame.innerHeight = m.GetInnerRect()
+
+	repaint := !m.damageTracking || m.invalidated || frame.overlay || frame != m.drawnFrame || m.cleared(screen)
+	m.drawnFrame, m.invalidated = frame, false
+
+	return repaint
+}
+
+// edgeCell is a cell on the edge of the manager as it was drawn
+type edgeCell struct {
+	x, y  int
+	mainc rune
+	style tcell.Style
+}
+
+// rememberEdges remembers the cells on the edges of the manager after it was
+// drawn
+func (m *Manager) rememberEdges(screen tcell.Screen) {
+	x, y, width, height := m.GetRect()
+
+	m.drawnEdges = m.drawnEdges[:0]
+	remember := func(x, y int) {
+		mainc, _, style, _ := screen.GetContent(x, y)
+		m.drawnEdges = append(m.drawnEdges, edgeCell{x, y, mainc, style})
+	}
+	for column := x; column < x+width; column++ {
+		remember(column, y)
+		remember(column, y+height-1)
+	}
+	for row := y + 1; row < y+height-1; row++ {
+		remember(x, row)
+		remember(x+width-1, row)
+	}
+}
+
+// cleared returns true if the edges of the manager changed since it was drawn,
+// e.g. because the application cleared the screen after a resize event
+func (m *Manager) cleared(screen tcell.Screen) bool {
+	for _, cell := range m.drawnEdges {
+		mainc, _, style, _ := screen.GetContent(cell.x, cell.y)
+		if mainc != cell.mainc || style != cell.style {
+			return true
+		}
+	}
+	return false
+}
+
+// Invalidate makes the manager draw all its cells on the next draw. With damage
+// tracking the windows which did not change are not drawn again. A cleared
+// screen is noticed on the edges of the manager, but something else drawn
+// over the windows, e.g. a modal which is no longer shown, is not.
+func (m *Manager) Invalidate() *Manager {
+	m.invalidated = true
+	return m
+}
+
+// inheritDamageTracking passes the damage tracking flag down the primitive tree
+// to every layout and window
+func inheritDamageTracking(p tview.Primitive, enabled bool) {
+	switch p := p.(type) {
+	case *Layout:
+		p.damageTracking = enabled
+		for _, item := range p.items {
+			inheritDamageTracking(item.Primitive, enabled)
+		}
+	case *Window:
+		if !enabled {
+			p.cache = nil
+		}
+		p.damageTracking = enabled
+	}
+}
+
+// SetDamageTracking sets whether windows are drawn from a cache while they do
+// not change. A window is redrawn when its size, focus, title, buttons or
+// theme change, when it receives a key or mouse event and when it is marked
+// with MarkDirty, which is needed when its content changes otherwise, e.g.
+// from a goroutine.
+//
+// Windows which did not change are not drawn to the screen at all, and
+// layouts only draw their background and splitters when they change, as long
+// as the manager keeps its size and no menu or layer is shown. Something else
+// drawing over the manager has to invalidate it, see Invalidate.
+func (m *Manager) SetDamageTracking(enabled bool) *Manager {
+	m.damageTracking = enabled
+	return m
+}
+
+// HasDamageTracking returns true if windows are drawn from a cache
+func (m *Manager) HasDamageTracking() bool {
+	return m.damageTracking
+}
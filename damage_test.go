@@ -0,0 +1,161 @@
+package tilman
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newPaneManager creates a manager with 20 windows in 4 columns of 5
+func newPaneManager(tracking bool) (*Manager, []*Window) {
+	var windows []*Window
+
+	root := NewLayout().SetDirection(HorizontalLayout).SetSplitter(true)
+	for column := 0; column < 4; column++ {
+		layout := NewLayout().SetDirection(VerticalLayout).SetSplitter(true)
+		for row := 0; row < 5; row++ {
+			text := tview.NewTextView().SetText(fmt.Sprintf("pane %d.%d\nsome text", column, row))
+			window := NewWindow().SetRoot(text).SetTitle(fmt.Sprintf("pane %d.%d", column, row))
+			layout.AddItemWeight(window, 1)
+			windows = append(windows, window)
+		}
+		root.AddItemWeight(layout, 1)
+	}
+
+	return NewWindowManager().SetRoot(root).SetDamageTracking(tracking), windows
+}
+
+// damageTwin is a manager drawn to its own screen, the steps of the test are
+// applied to a manager with damage tracking and one without
+type damageTwin struct {
+	manager *Manager
+	windows []*Window
+	screen  tcell.SimulationScreen
+	focus   *focuser
+}
+
+func (d *damageTwin) mouse(action tview.MouseAction, x, y int) {
+	event := tcell.NewEventMouse(x, y, tcell.ButtonNone, tcell.ModNone)
+	d.manager.MouseHandler()(action, event, d.focus.setFocus)
+}
+
+func (d *damageTwin) key(key tcell.Key) {
+	d.manager.InputHandler()(tcell.NewEventKey(key, 0, tcell.ModNone), d.focus.setFocus)
+}
+
+func (d *damageTwin) resize(width, height int) {
+	d.screen.SetSize(width, height)
+	d.screen.Clear()
+	d.manager.SetRect(0, 0, width, height)
+}
+
+func TestDamageTrackingScreens(t *testing.T) {
+	var twins []*damageTwin
+	for _, tracking := range []bool{false, true} {
+		manager, windows := newPaneManager(tracking)
+		for _, w := range windows {
+			w.SetBorder(true)
+		}
+		manager.SetDimUnfocused(DimBlend)
+		manager.SetRect(0, 0, 80, 30)
+		twins = append(twins, &damageTwin{
+			manager: manager,
+			windows: windows,
+			screen:  newSimulationScreen(t, 80, 30),
+			focus:   &focuser{},
+		})
+	}
+
+	steps := []struct {
+		name string
+		step func(d *damageTwin)
+	}{
+		{"first draw", func(d *damageTwin) {}},
+		{"focus", func(d *damageTwin) { d.focus.setFocus(d.manager) }},
+		{"drag a splitter", func(d *damageTwin) {
+			x, _, _, _ := d.windows[5].GetRect()
+			d.mouse(tview.MouseLeftDown, x-1, 3)
+			d.mouse(tview.MouseMove, x+4, 3)
+			d.mouse(tview.MouseLeftUp, x+4, 3)
+		}},
+		{"drag a splitter back", func(d *damageTwin) {
+			_, y, _, _ := d.windows[1].GetRect()
+			d.mouse(tview.MouseLeftDown, 2, y-1)
+			d.mouse(tview.MouseMove, 2, y-3)
+			d.mouse(tview.MouseLeftUp, 2, y-3)
+		}},
+		{"click another window", func(d *damageTwin) {
+			x, y, _, _ := d.windows[12].GetRect()
+			d.mouse(tview.MouseLeftClick, x+2, y+1)
+		}},
+		{"focus with the keyboard", func(d *damageTwin) { d.focus.setFocus(d.windows[3]) }},
+		{"change a text", func(d *damageTwin) {
+			d.windows[7].GetRoot().(*tview.TextView).SetText("changed")
+			d.windows[7].MarkDirty()
+		}},
+		{"open the menu", func(d *damageTwin) {
+			x, y, _, _ := d.windows[8].GetRect()
+			d.mouse(tview.MouseRightClick, x+1, y)
+		}},
+		{"move in the menu", func(d *damageTwin) { d.key(tcell.KeyDown) }},
+		{"close the menu", func(d *damageTwin) { d.key(tcell.KeyEscape) }},
+		{"maximize", func(d *damageTwin) { d.manager.Maximize(d.windows[8]) }},
+		{"restore", func(d *damageTwin) { d.manager.Restore() }},
+		{"shrink", func(d *damageTwin) { d.resize(60, 20) }},
+		{"grow", func(d *damageTwin) { d.resize(90, 35) }},
+		{"add a layer", func(d *damageTwin) {
+			d.manager.AddLayer(NewLayer(NewWindow().SetRoot(tview.NewBox()).SetBorder(true), 10, 5, 30, 10))
+		}},
+		{"remove the layer", func(d *damageTwin) { d.manager.RemoveLayer(d.manager.GetLayers()[0]) }},
+		{"float a window", func(d *damageTwin) { d.manager.Float(d.windows[2]) }},
+		{"dock the window", func(d *damageTwin) { d.manager.Dock(d.windows[2]) }},
+		{"draw again", func(d *damageTwin) {}},
+	}
+
+	for _, step := range steps {
+		for _, d := range twins {
+			step.step(d)
+			d.manager.Draw(d.screen)
+			d.screen.Show()
+		}
+
+		want, _, _ := twins[0].screen.GetContents()
+		got, _, _ := twins[1].screen.GetContents()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("after %q the screen with damage tracking is\n%s\nwant\n%s",
+				step.name, screenText(twins[1].screen), screenText(twins[0].screen))
+		}
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	tests := []struct {
+		name     string
+		tracking bool
+		dirty    bool
+	}{
+		{name: "tracking off"},
+		{name: "tracking on", tracking: true},
+		{name: "tracking on, one window dirty", tracking: true, dirty: true},
+	}
+
+	for _, test := range tests {
+		b.Run(test.name, func(b *testing.B) {
+			screen := newSimulationScreen(b, 200, 60)
+			manager, windows := newPaneManager(test.tracking)
+			manager.SetRect(0, 0, 200, 60)
+			manager.Draw(screen)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if test.dirty {
+					windows[i%len(windows)].MarkDirty()
+				}
+				manager.Draw(screen)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// SetFooter sets the left, center and right aligned segments of the footer and
+// shows it. Segments may contain color tags.
+func (w *Window) SetFooter(left, center, right string) *Window {
+	w.footer = [3]string{left, center, right}
+	w.footerShown = true
+	return w
+}
+
+// SetFooterText sets one segment of the footer, selected by its alignment
+// (tview.AlignLeft, tview.AlignCenter or tview.AlignRight), and shows it
+func (w *Window) SetFooterText(align int, text string) *Window {
+	switch align {
+	case tview.AlignLeft:
+		w.footer[0] = text
+	case tview.AlignCenter:
+		w.footer[1] = text
+	case tview.AlignRight:
+		w.footer[2] = text
+	}
+	w.footerShown = true
+	return w
+}
+
+// GetFooter returns the left, center and right segments of the footer
+func (w *Window) GetFooter() (string, string, string) {
+	return w.footer[0], w.footer[1], w.footer[2]
+}
+
+// ShowFooter sets whether the footer row is drawn at the bottom of the window.
+// The footer takes a row from the content unless it is drawn on the bottom
+// border.
+func (w *Window) ShowFooter(show bool) *Window {
+	w.footerShown = show
+	return w
+}
+
+// HasFooter returns true if the footer is shown
+func (w *Window) HasFooter() bool {
+	return w.footerShown
+}
+
+// hasFooterRow returns true if the window has a bottom row for the footer
+// buttons, either the footer or the bottom border
+func (w *Window) hasFooterRow() bool {
+	return w.footerShown || w.border && !w.currentBorderStyle().TitleOnly
+}
+
+// drawFooter draws the segments of the footer on the last row of the window,
+// between the footer buttons. Without bottom border, the row is drawn like
+// the title bar.
+func (w *Window) drawFooter(screen tcell.Screen, x, y, width, height int, glyphs *BorderGlyphs, borderStyle, textStyle tcell.Style) {
+	footerY := y + height - 1
+
+	if w.border && w.currentBorderStyle().TitleOnly {
+		for x_ := x; x_ < x+width; x_++ {
+			screen.SetContent(x_, footerY, glyphs.Horizontal, nil, borderStyle)
+		}
+	}
+
+	// keep the segments off the buttons
+	start, end := w.freeSpan(true, x+1, x+width-1)
+
+	if end-start < 1 {
+		return
+	}
+
+	for i, align := range []int{tview.AlignCenter, tview.AlignLeft, tview.AlignRight} {
+		text := w.footer[[]int{1, 0, 2}[i]]
+		if text != "" {
+			styledPrint(screen, text, start, footerY, end-start, align, textStyle)
+		}
+	}
+}
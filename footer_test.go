@@ -0,0 +1,95 @@
+package tilman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestFooter(t *testing.T) {
+	w := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetBorderStyle(BorderSingle).
+		SetFooter("L", "mid", "R")
+	screen := drawWindow(t, w, 20, 5)
+
+	checkCells(t, screen, "bordered", map[[2]int]rune{
+		{1, 4}:  'L',
+		{8, 4}:  'm',
+		{10, 4}: 'd',
+		{18, 4}: 'R',
+		{0, 4}:  SingleBorderGlyphs.BottomLeft,
+	})
+	// the footer is drawn on the bottom border
+	if x, y, width, height := w.GetInnerRect(); x != 1 || y != 1 || width != 18 || height != 3 {
+		t.Errorf("the inner rect is %d,%d %dx%d, want 1,1 18x3", x, y, width, height)
+	}
+
+	// with a title only border the footer is drawn like the title bar
+	w.SetBorderStyle(BorderTitleOnly)
+	screen = drawWindow(t, w, 20, 5)
+	checkCells(t, screen, "title only", map[[2]int]rune{
+		{0, 4}:  SingleBorderGlyphs.Horizontal,
+		{1, 4}:  'L',
+		{18, 4}: 'R',
+	})
+	if x, y, width, height := w.GetInnerRect(); x != 0 || y != 1 || width != 20 || height != 3 {
+		t.Errorf("the inner rect is %d,%d %dx%d, want 0,1 20x3", x, y, width, height)
+	}
+
+	// without border the footer takes the last row of the content
+	w.SetBorder(false).SetFooterText(tview.AlignCenter, "")
+	screen = drawWindow(t, w, 20, 5)
+	checkCells(t, screen, "borderless", map[[2]int]rune{
+		{1, 4}:  'L',
+		{9, 4}:  ' ',
+		{18, 4}: 'R',
+	})
+	if x, y, width, height := w.GetInnerRect(); x != 0 || y != 1 || width != 20 || height != 3 {
+		t.Errorf("the inner rect is %d,%d %dx%d, want 0,1 20x3", x, y, width, height)
+	}
+
+	w.ShowFooter(false)
+	screen = drawWindow(t, w, 20, 5)
+	if text := screenText(screen); strings.ContainsAny(text, "LR") {
+		t.Errorf("the hidden footer is drawn:\n%s", text)
+	}
+	if left, center, right := w.GetFooter(); left != "L" || center != "" || right != "R" {
+		t.Errorf("the footer is %q, %q, %q", left, center, right)
+	}
+}
+
+func TestFooterButtons(t *testing.T) {
+	clicks := 0
+	w := newButtonWindow(&clicks)
+	w.SetBorderStyle(BorderSingle)
+	w.GetButton(0).Footer = true
+	w.SetFooter("", "", "R")
+
+	screen := drawWindow(t, w, 20, 5)
+	x, y := buttonCell(w, 0)
+	if x != 16 || y != 4 {
+		t.Errorf("the footer button is at %d,%d, want 16,4", x, y)
+	}
+	// the segments keep off the buttons
+	checkCells(t, screen, "footer", map[[2]int]rune{
+		{14, 4}: 'R',
+		{17, 4}: 'x',
+	})
+
+	mouse(w, tview.MouseLeftDown, 17, 4)
+	mouse(w, tview.MouseLeftUp, 17, 4)
+	if clicks != 1 {
+		t.Errorf("the footer button was clicked %d times, want 1", clicks)
+	}
+
+	// the bottom border shows the buttons without the footer, the title only
+	// border does not
+	w.ShowFooter(false)
+	if screen = drawWindow(t, w, 20, 5); !strings.Contains(screenText(screen), "[x]") {
+		t.Errorf("the footer button is not drawn on the bottom border:\n%s", screenText(screen))
+	}
+	w.SetBorderStyle(BorderTitleOnly)
+	if screen = drawWindow(t, w, 20, 5); strings.Contains(screenText(screen), "[x]") {
+		t.Errorf("the footer button is drawn without footer row:\n%s", screenText(screen))
+	}
+}
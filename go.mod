@@ -1,8 +1,9 @@
 module github.com/axard/tilman
 
-go 1.15
+go 1.16
 
 require (
 	github.com/gdamore/tcell/v2 v2.1.0
+	github.com/mattn/go-runewidth v0.0.10
 	github.com/rivo/tview v0.0.0-20210217110421-8a8f78a6dd01
 )
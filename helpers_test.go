@@ -0,0 +1,76 @@
+package tilman
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func newSimulationScreen(t testing.TB, width, height int) tcell.SimulationScreen {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(width, height)
+	return screen
+}
+
+// screenText returns the text shown on the screen, one line per row
+func screenText(screen tcell.SimulationScreen) string {
+	screen.Show()
+	cells, width, height := screen.GetContents()
+
+	var b strings.Builder
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if runes := cells[y*width+x].Runes; len(runes) > 0 {
+				b.WriteString(string(runes))
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// cellStyle returns the style of the cell drawn on the screen
+func cellStyle(screen tcell.Screen, x, y int) tcell.Style {
+	_, _, style, _ := screen.GetContent(x, y)
+	return style
+}
+
+// focuser gives the focus like tview.Application.SetFocus, it is only called
+// by the handlers of the manager, which hold the lock
+type focuser struct {
+	focused tview.Primitive
+}
+
+func (f *focuser) setFocus(p tview.Primitive) {
+	if f.focused != nil {
+		f.focused.Blur()
+	}
+	f.focused = p
+	p.Focus(f.setFocus)
+}
+
+// within fails the test if the function does not return in time, e.g.
+// because it deadlocks
+func within(t *testing.T, what string, f func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		f()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s did not return, deadlock?", what)
+	}
+}
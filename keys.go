@@ -0,0 +1,173 @@
+package tilman
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// KeyBinding describes a key combination such as "Alt+M", "Ctrl+N" or "F2"
+type KeyBinding struct {
+	Key       tcell.Key
+	Rune      rune // the character for tcell.KeyRune keys
+	Modifiers tcell.ModMask
+}
+
+var keysByName = func() map[string]tcell.Key {
+	keys := make(map[string]tcell.Key, len(tcell.KeyNames))
+	for key, name := range tcell.KeyNames {
+		keys[strings.ToLower(name)] = key
+	}
+	return keys
+}()
+
+// ParseKeyBinding parses a key combination written as modifiers (Shift, Ctrl,
+// Alt, Meta) and a key joined by '+', e.g. "Alt+X", "Ctrl+Space" or "PgDn".
+// Keys are either single characters or the names used by tcell.KeyNames.
+func ParseKeyBinding(text string) (KeyBinding, error) {
+	var binding KeyBinding
+
+	parts := strings.Split(text, "+")
+	// a trailing "+" is the plus key itself, e.g. "Ctrl++"
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = append(parts[:len(parts)-2], "+")
+	}
+
+	for _, modifier := range parts[:len(parts)-1] {
+		switch strings.ToLower(strings.TrimSpace(modifier)) {
+		case "shift":
+			binding.Modifiers |= tcell.ModShift
+		case "ctrl":
+			binding.Modifiers |= tcell.ModCtrl
+		case "alt":
+			binding.Modifiers |= tcell.ModAlt
+		case "meta":
+			binding.Modifiers |= tcell.ModMeta
+		default:
+			return binding, fmt.Errorf("unknown modifier %q in key %q", modifier, text)
+		}
+	}
+
+	key := parts[len(parts)-1]
+	if key != " " {
+		key = strings.TrimSpace(key)
+	}
+
+	switch {
+	case key == "":
+		return binding, fmt.Errorf("missing key in %q", text)
+	case utf8.RuneCountInString(key) == 1:
+		r, _ := utf8.DecodeRuneInString(key)
+		if binding.Modifiers&tcell.ModCtrl != 0 && r < unicode.MaxASCII && unicode.IsLetter(r) {
+			// tcell reports Ctrl+letter as a control key
+			binding.Key = tcell.KeyCtrlA + tcell.Key(unicode.ToLower(r)-'a')
+		} else {
+			binding.Key = tcell.KeyRune
+			binding.Rune = r
+		}
+	case strings.EqualFold(key, "space"):
+		binding.Key = tcell.KeyRune
+		binding.Rune = ' '
+		if binding.Modifiers&tcell.ModCtrl != 0 {
+			binding.Key = tcell.KeyCtrlSpace
+			binding.Rune = 0
+		}
+	default:
+		k, ok := keysByName[strings.ToLower(key)]
+		if !ok {
+			return binding, fmt.Errorf("unknown key %q in %q", key, text)
+		}
+		binding.Key = k
+
+		// terminals report Shift+Tab as a key of its own
+		if k == tcell.KeyTab && binding.Modifiers&tcell.ModShift != 0 {
+			binding.Key = tcell.KeyBacktab
+			binding.Modifiers &^= tcell.ModShift
+		}
+	}
+
+	return binding, nil
+}
+
+// Matches returns true if the key event is this key combination. Characters
+// are compared ignoring their case and the Shift modifier.
+func (k KeyBinding) Matches(event *tcell.EventKey) bool {
+	if k.Key == tcell.KeyRune {
+		return event.Key() == tcell.KeyRune &&
+			unicode.ToLower(event.Rune()) == unicode.ToLower(k.Rune) &&
+			event.Modifiers()&^tcell.ModShift == k.Modifiers&^tcell.ModShift
+	}
+
+	if event.Key() != k.Key {
+		return false
+	}
+
+	// tcell reports control keys with or without the Ctrl modifier
+	if k.Key >= tcell.KeyCtrlSpace && k.Key <= tcell.KeyCtrlUnderscore {
+		return event.Modifiers()|tcell.ModCtrl == k.Modifiers|tcell.ModCtrl
+	}
+
+	return event.Modifiers() == k.Modifiers
+}
+
+// normalized returns the binding as compared by Matches, bindings matching the
+// same events are equal once normalized
+func (k KeyBinding) normalized() KeyBinding {
+	if k.Key == tcell.KeyRune {
+		k.Rune = unicode.ToLower(k.Rune)
+		k.Modifiers &^= tcell.ModShift
+	} else if k.Key >= tcell.KeyCtrlSpace && k.Key <= tcell.KeyCtrlUnderscore {
+		k.Modifiers |= tcell.ModCtrl
+	}
+	return k
+}
+
+// IsZero returns true if the binding is not set
+func (k KeyBinding) IsZero() bool {
+	return k == KeyBinding{}
+}
+
+// String returns the key combination in the format accepted by ParseKeyBinding
+func (k KeyBinding) String() string {
+	if k.IsZero() {
+		return ""
+	}
+
+	var parts []string
+	for _, modifier := range []struct {
+		mask tcell.ModMask
+		name string
+	}{
+		{tcell.ModShift, "Shift"},
+		{tcell.ModAlt, "Alt"},
+		{tcell.ModMeta, "Meta"},
+		{tcell.ModCtrl, "Ctrl"},
+	} {
+		if k.Modifiers&modifier.mask != 0 {
+			parts = append(parts, modifier.name)
+		}
+	}
+
+	switch {
+	case k.Key == tcell.KeyRune && k.Rune == ' ':
+		parts = append(parts, "Space")
+	case k.Key == tcell.KeyRune:
+		parts = append(parts, string(k.Rune))
+	case k.Key >= tcell.KeyCtrlA && k.Key <= tcell.KeyCtrlZ && k.Modifiers&tcell.ModCtrl != 0:
+		parts = append(parts, string(rune('A'+k.Key-tcell.KeyCtrlA)))
+	default:
+		name, ok := tcell.KeyNames[k.Key]
+		if !ok {
+			name = fmt.Sprintf("Key[%d]", k.Key)
+		}
+		if k.Modifiers&tcell.ModCtrl != 0 {
+			name = strings.TrimPrefix(name, "Ctrl-")
+		}
+		parts = append(parts, name)
+	}
+
+	return strings.Join(parts, "+")
+}
@@ -0,0 +1,212 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Layer is a primitive drawn over the layouts of the manager, such as a
+// floating window. It is drawn into an off-screen buffer with (0,0) in its
+// top-left corner, which is then composited onto the screen.
+type Layer struct {
+	Primitive tview.Primitive
+
+	// position on the screen and size of the layer
+	X, Y, Width, Height int
+
+	Mode    CompositeMode // how the layer is combined with what is below
+	Opacity float64       // from 0 to 1, used by CompositeBlend
+	Shadow  bool          // draw a drop shadow to the right and below
+
+	buffer *Buffer
+}
+
+// NewLayer creates an opaque layer showing the primitive in the given area
+func NewLayer(p tview.Primitive, x, y, width, height int) *Layer {
+	return &Layer{
+		Primitive: p,
+		X:         x,
+		Y:         y,
+		Width:     width,
+		Height:    height,
+		Opacity:   1,
+	}
+}
+
+// contain returns true if the screen position is on the layer
+func (l *Layer) contain(x, y int) bool {
+	return x >= l.X && y >= l.Y && x < l.X+l.Width && y < l.Y+l.Height
+}
+
+// draw renders the primitive off-screen and composites it onto the screen
+func (l *Layer) draw(screen tcell.Screen) {
+	if l.buffer == nil {
+		l.buffer = NewBuffer(l.Width, l.Height)
+	} else {
+		l.buffer.SetSize(l.Width, l.Height)
+	}
+
+	l.Primitive.SetRect(0, 0, l.Width, l.Height)
+	expose(l.Primitive, true)
+	l.Primitive.Draw(l.buffer)
+
+	l.buffer.Composite(screen, l.X, l.Y, l.Mode, l.Opacity)
+	if l.Shadow {
+		drawShadow(screen, l.X, l.Y, l.Width, l.Height)
+	}
+}
+
+// AddLayer adds a layer on top of the layouts and the other layers
+func (m *Manager) AddLayer(layer *Layer) *Manager {
+	m.layers = append(m.layers, layer)
+	return m
+}
+
+// RemoveLayer removes the layer from the manager
+func (m *Manager) RemoveLayer(layer *Layer) *Manager {
+	for i, l := range m.layers {
+		if l == layer {
+			m.layers = append(m.layers[:i], m.layers[i+1:]...)
+			break
+		}
+	}
+	return m
+}
+
+// RaiseLayer moves the layer on top of the other layers
+func (m *Manager) RaiseLayer(layer *Layer) *Manager {
+	return m.RemoveLayer(layer).AddLayer(layer)
+}
+
+// GetLayers returns the layers from the bottom to the top
+func (m *Manager) GetLayers() []*Layer {
+	return m.layers
+}
+
+// layerPrimitives returns the primitives of the layers from the bottom to the
+// top
+func (m *Manager) layerPrimitives() []tview.Primitive {
+	primitives := make([]tview.Primitive, len(m.layers))
+	for i, layer := range m.layers {
+		primitives[i] = layer.Primitive
+	}
+	return primitives
+}
+
+// focusedLayer returns the layer whose primitive has focus, if any
+func (m *Manager) focusedLayer() *Layer {
+	for _, layer := range m.layers {
+		if layer.Primitive.HasFocus() {
+			return layer
+		}
+	}
+	return nil
+}
+
+// layerAt returns the topmost layer at the screen position, if any
+func (m *Manager) layerAt(x, y int) *Layer {
+	for i := len(m.layers) - 1; i >= 0; i-- {
+		if m.layers[i].contain(x, y) {
+			return m.layers[i]
+		}
+	}
+	return nil
+}
+
+// layerMouse passes the mouse events to the primitive of a layer, or to the
+// primitive it contains which captured the mouse, with the position translated
+// to the coordinates of the layer
+type layerMouse struct {
+	tview.Primitive
+	layer *Layer
+}
+
+// MouseHandler returns the handler of the primitive translating the position,
+// a primitive capturing the mouse is wrapped too
+func (p *layerMouse) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	return func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+		handler := p.Primitive.MouseHandler()
+		if handler == nil {
+			return false, nil
+		}
+
+		x, y := event.Position()
+		translated := tcell.NewEventMouse(x-p.layer.X, y-p.layer.Y, event.Buttons(), event.Modifiers())
+		consumed, capture := handler(action, translated, setFocus)
+		if capture != nil {
+			capture = &layerMouse{capture, p.layer}
+		}
+		return consumed, capture
+	}
+}
+
+// dockPosition is where a floating window was in the layouts
+type dockPosition struct {
+	layout *Layout
+	index  int
+	size   int
+	unit   SizeUnit
+}
+
+// Float moves the window from its layout to a layer with a shadow at the same
+// place on the screen, a maximized window is restored first. Dock moves it
+// back.
+func (m *Manager) Float(w *Window) *Manager {
+	l, item := findItem(m.logicalRoot, w)
+	if item == nil {
+		return m
+	}
+	if m.IsMaximazed(w) {
+		m.Restore()
+	}
+	m.Unminimize(w)
+
+	x, y, width, height := w.GetRect()
+	for i, other := range l.items {
+		if other == item {
+			w.docked = &dockPosition{layout: l, index: i, size: item.Size, unit: item.Unit}
+			l.RemoveItem(i)
+			break
+		}
+	}
+
+	layer := NewLayer(w, x, y, width, height)
+	layer.Shadow = true
+	return m.AddLayer(layer)
+}
+
+// Dock moves the floating window back to its place in the layout it was
+// floated from, or to the end of the root layout if that layout is gone
+func (m *Manager) Dock(w *Window) *Manager {
+	layer := m.windowLayer(w)
+	if layer == nil {
+		return m
+	}
+	m.RemoveLayer(layer)
+
+	position := dockPosition{layout: m.GetRoot(), index: -1, size: 1, unit: WeightUnit}
+	if d := w.docked; d != nil {
+		if _, item := findItem(m.logicalRoot, d.layout); item != nil || d.layout == m.logicalRoot {
+			position = *d
+		}
+	}
+	w.docked = nil
+
+	position.layout.insertItem(position.index, &Item{Primitive: w, Size: position.size, Unit: position.unit})
+	return m
+}
+
+// IsFloating returns true if the window is shown in a layer
+func (m *Manager) IsFloating(w *Window) bool {
+	return m.windowLayer(w) != nil
+}
+
+// windowLayer returns the layer showing the window, if any
+func (m *Manager) windowLayer(w *Window) *Layer {
+	for _, layer := range m.layers {
+		if layer.Primitive == w {
+			return layer
+		}
+	}
+	return nil
+}
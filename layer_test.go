@@ -0,0 +1,168 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// mouseTracker records the positions of the mouse events it receives and
+// captures the mouse while the left button is down
+type mouseTracker struct {
+	*tview.Box
+	positions [][2]int
+}
+
+func (p *mouseTracker) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	return func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+		x, y := event.Position()
+		p.positions = append(p.positions, [2]int{x, y})
+		if action == tview.MouseLeftDown {
+			return true, p
+		}
+		return true, nil
+	}
+}
+
+// newLayerManager creates a 40x10 manager with two bordered windows side by
+// side
+func newLayerManager() (*Manager, *Window, *Window) {
+	left := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle("left")
+	right := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle("right")
+	root := NewLayout().SetDirection(HorizontalLayout).
+		AddItem(left, 10).
+		AddItemWeight(right, 1)
+
+	m := NewWindowManager().SetRoot(root)
+	m.SetRect(0, 0, 40, 10)
+	return m, left, right
+}
+
+func TestLayerMouse(t *testing.T) {
+	m, _, _ := newLayerManager()
+	focus := &focuser{}
+	tracker := &mouseTracker{Box: tview.NewBox()}
+	m.AddLayer(NewLayer(tracker, 5, 2, 10, 4))
+	m.Draw(newSimulationScreen(t, 40, 10))
+
+	send := func(action tview.MouseAction, x, y int) tview.Primitive {
+		event := tcell.NewEventMouse(x, y, tcell.ButtonNone, tcell.ModNone)
+		_, capture := m.MouseHandler()(action, event, focus.setFocus)
+		return capture
+	}
+
+	// the positions are relative to the layer, also while it captures the
+	// mouse outside of it, the events outside go to the windows once it is
+	// released
+	if send(tview.MouseMove, 6, 3) != nil {
+		t.Error("the manager captured the mouse without capture of the layer")
+	}
+	if send(tview.MouseLeftDown, 14, 5) != m {
+		t.Error("the manager did not capture the mouse for the layer")
+	}
+	send(tview.MouseMove, 30, 8)
+	send(tview.MouseLeftUp, 30, 8)
+	send(tview.MouseMove, 30, 8)
+
+	want := [][2]int{{1, 1}, {9, 3}, {25, 6}}
+	if len(tracker.positions) != len(want) {
+		t.Fatalf("the layer received the positions %v, want %v", tracker.positions, want)
+	}
+	for i := range want {
+		if tracker.positions[i] != want[i] {
+			t.Errorf("the event %d is at %v, want %v", i, tracker.positions[i], want[i])
+		}
+	}
+}
+
+func TestLayerFocus(t *testing.T) {
+	m, left, _ := newLayerManager()
+	focus := &focuser{}
+	screen := newSimulationScreen(t, 40, 10)
+
+	floating := NewWindow().SetRoot(tview.NewBox()).SetBorder(true)
+	m.AddLayer(NewLayer(floating, 5, 2, 10, 4))
+
+	focus.setFocus(m)
+	if !left.HasFocus() {
+		t.Fatal("the first window was not focused")
+	}
+
+	// the manager gives the focus back to the layer which had it
+	focus.setFocus(floating)
+	m.Draw(screen)
+	if m.FocusedWindow() != floating {
+		t.Error("the floating window is not the focused window")
+	}
+	focus.setFocus(tview.NewBox())
+	m.Draw(screen)
+	focus.setFocus(m)
+	if !floating.HasFocus() {
+		t.Error("the focus did not go back to the floating window")
+	}
+
+	// unless it was removed
+	m.RemoveLayer(m.GetLayers()[0])
+	focus.setFocus(tview.NewBox())
+	focus.setFocus(m)
+	if !left.HasFocus() {
+		t.Error("the focus went to the removed layer")
+	}
+}
+
+func TestFloatDock(t *testing.T) {
+	m, left, right := newLayerManager()
+	focus := &focuser{}
+	m.Draw(newSimulationScreen(t, 40, 10))
+
+	m.Float(left)
+	layers := m.GetLayers()
+	if !m.IsFloating(left) || len(layers) != 1 || m.GetRoot().CountItems() != 1 {
+		t.Fatal("the window was not moved to a layer")
+	}
+	if l := layers[0]; l.X != 0 || l.Y != 0 || l.Width != 10 || l.Height != 10 || !l.Shadow {
+		t.Errorf("the layer is at %d,%d %dx%d, want the place of the window with a shadow", l.X, l.Y, l.Width, l.Height)
+	}
+
+	// the layer hides the title bar of the window below
+	layers[0].X, layers[0].Y = 20, 3
+	if m.titleBarAt(22, 3) != left || m.titleBarAt(22, 0) != right || m.titleBarAt(22, 4) != nil {
+		t.Error("the title bars are not found through the layer")
+	}
+
+	m.Draw(newSimulationScreen(t, 40, 10))
+	event := tcell.NewEventMouse(22, 3, tcell.Button2, tcell.ModNone)
+	m.MouseHandler()(tview.MouseRightClick, event, focus.setFocus)
+	if labels := menuLabels(m); len(labels) != 2 || labels[0] != "Dock" || labels[1] != "Close" {
+		t.Fatalf("the menu of the floating window offers %q", labels)
+	}
+	if x, y, _, _ := m.menu.rect(m.GetInnerRect()); x != 22 || y != 4 {
+		t.Errorf("the menu is at %d,%d, want below the click", x, y)
+	}
+	menuKeys(m, focus, tcell.KeyEnter)
+
+	root := m.GetRoot()
+	if m.IsFloating(left) || root.CountItems() != 2 || root.GetItem(0).Primitive != left ||
+		root.GetItem(0).Size != 10 || root.GetItem(0).Unit != FixedUnit {
+		t.Error("the window was not docked back at its place")
+	}
+	if !left.HasFocus() {
+		t.Error("the docked window was not focused")
+	}
+
+	// the floating window is closed with its layer, without its layout it is
+	// docked to the root
+	m.Float(right).Close(right)
+	if len(m.GetLayers()) != 0 || root.CountItems() != 1 {
+		t.Error("the floating window was not closed")
+	}
+	nested := NewLayout().AddItemWeight(right, 1)
+	root.AddItemWeight(nested, 1)
+	m.Float(right)
+	root.RemoveItem(1)
+	m.Dock(right)
+	if root.CountItems() != 2 || root.GetItem(1).Primitive != right {
+		t.Error("the window was not docked to the root")
+	}
+}
@@ -18,26 +18,62 @@ const (
 	AutoSize = 0
 )
 
+// SizeUnit defines how the size of a layout item is interpreted
+type SizeUnit int
+
+const (
+	// FixedUnit sizes are given in cells, AutoSize items share the free space
+	FixedUnit SizeUnit = iota
+	// PercentUnit sizes are given in percent of the layout space
+	PercentUnit
+	// WeightUnit items share the free space proportionally to their size
+	WeightUnit
+)
+
 type Item struct {
 	tview.Primitive
 
 	Size int
+	Unit SizeUnit
+}
+
+// weight returns the share of the free space the item takes, or 0 if the item
+// has a size of its own
+func (i *Item) weight() int {
+	switch i.Unit {
+	case WeightUnit:
+		if i.Size <= 0 {
+			return 1
+		}
+		return i.Size
+	case PercentUnit:
+		return 0
+	default:
+		if i.Size == AutoSize {
+			return 1
+		}
+		return 0
+	}
 }
 
 type splitter struct {
-	x, y [2]int // begin and end points
+	x, y [2]int // begin and end points of the area between the items
+
+	vertical bool // vertical splitter on horizontal direction
 
 	a, b *Item
 }
 
 func (s *splitter) contain(x, y int) bool {
-	if s.x[0] == s.x[1] {
-		// vertical splitter on horizontal direction
-		return s.x[0] == x && (s.y[0] <= y && y <= s.y[1])
-	} else {
-		// horizontal splitter on vertical direction
-		return s.y[0] == y && (s.x[0] <= x && x <= s.x[1])
+	return s.x[0] <= x && x <= s.x[1] && s.y[0] <= y && y <= s.y[1]
+}
+
+// line returns the position of the splitter line, in the middle of its area
+func (s *splitter) line() int {
+	if s.vertical {
+		return (s.x[0] + s.x[1]) / 2
 	}
+	return (s.y[0] + s.y[1]) / 2
 }
 
 type Layout struct {
@@ -57,6 +93,18 @@ type Layout struct {
 	// The border style.
 	splitterStyle tcell.Style
 
+	// Whether the borders of the windows are collapsed into the splitters, set
+	// on the layout or inherited from a collapsed parent layout
+	collapsed, inheritedCollapsed bool
+
+	// The gap between the items in cells, set on the layout (or negative) and
+	// inherited from the window manager, and the padding around the items
+	gap, inheritedGap, padding int
+
+	// Whether the window manager shows a single window, which disables the gaps
+	// and the padding
+	singleWindow bool
+
 	direction Direction
 
 	x, y, width, height int
@@ -64,6 +112,19 @@ type Layout struct {
 	// The layout's background color.
 	backgroundColor tcell.Color
 
+	// The theme of the layout and the theme inherited from the parent, if any
+	theme, inheritedTheme *Theme
+
+	// The glyphs of the window manager, if any
+	inheritedBorders *BorderSet
+
+	// Whether the background and splitters are only drawn when they change,
+	// whether the parent was drawn over the layout and the state and item
+	// sizes they were last drawn with
+	damageTracking, exposed bool
+	drawnState              layoutState
+	drawnSizes              []int
+
 	// An optional capture function which receives a key event and returns the
 	// event to be forwarded to the primitive's default input handler (nil if
 	// nothing should be forwarded).
@@ -79,6 +140,7 @@ func NewLayout() *Layout {
 	layout := &Layout{
 		backgroundColor:       tview.Styles.PrimitiveBackgroundColor,
 		focusedSplitterNumber: -1,
+		gap:                   -1,
 		splitterStyle:         tcell.StyleDefault.Foreground(tview.Styles.BorderColor),
 	}
 
@@ -107,6 +169,83 @@ func (l *Layout) SetSplitter(show bool) *Layout {
 	return l
 }
 
+// SetGap sets the number of empty cells between the items in addition to the
+// splitter, which is drawn in the middle of the gap and can be dragged anywhere
+// in it. A negative gap uses the gap set on the window manager. Gaps are
+// ignored when the borders are collapsed and, like the gaps of the window
+// manager, while the manager shows only one window.
+func (l *Layout) SetGap(gap int) *Layout {
+	l.gap = gap
+	l.rebuildSplitters()
+	return l
+}
+
+// GetGap returns the gap set on the layout, negative if it uses the gap of
+// the window manager
+func (l *Layout) GetGap() int {
+	return l.gap
+}
+
+// SetPadding sets the number of empty cells around the items of the layout.
+// Padding is ignored when the borders are collapsed and while the window
+// manager shows only one window.
+func (l *Layout) SetPadding(padding int) *Layout {
+	l.padding = padding
+	l.rebuildSplitters()
+	return l
+}
+
+// GetPadding returns the padding around the items of the layout
+func (l *Layout) GetPadding() int {
+	return l.padding
+}
+
+// separatorWidth returns the number of cells between two items
+func (l *Layout) separatorWidth() int {
+	if l.isCollapsed() {
+		return 1
+	}
+
+	gap := l.gap
+	if gap < 0 {
+		gap = l.inheritedGap
+	}
+	if gap < 0 || l.singleWindow {
+		gap = 0
+	}
+
+	return gap + 1
+}
+
+// inheritGap passes the gap of the window manager down the primitive tree,
+// and whether the manager shows a single window
+func inheritGap(p tview.Primitive, gap int, singleWindow bool) {
+	if l, ok := p.(*Layout); ok {
+		l.inheritedGap = gap
+		l.singleWindow = singleWindow
+		for _, item := range l.items {
+			inheritGap(item.Primitive, gap, singleWindow)
+		}
+	}
+}
+
+// SetCollapsedBorders sets the flag indicating whether or not the borders of the
+// windows are collapsed into the splitters. A collapsed layout draws a frame
+// around its items and a splitter between each of them, with junctions where
+// the lines of nested layouts meet. Bordered windows draw their title bar on
+// the line above them instead of a border of their own. Nested layouts are
+// collapsed as well.
+func (l *Layout) SetCollapsedBorders(collapsed bool) *Layout {
+	l.collapsed = collapsed
+	l.rebuildSplitters()
+	return l
+}
+
+// HasCollapsedBorders returns true if the borders of the layout are collapsed
+func (l *Layout) HasCollapsedBorders() bool {
+	return l.isCollapsed()
+}
+
 // SetSplitterColor sets the layout's splitter color.
 func (l *Layout) SetSplitterColor(color tcell.Color) *Layout {
 	l.splitterStyle = l.splitterStyle.Foreground(color)
@@ -139,37 +278,116 @@ func (l *Layout) GetBackgroundColor() tcell.Color {
 	return l.backgroundColor
 }
 
-func (l *Layout) itemsAmount() (int, int) {
-	auto := 0
-	for _, item := range l.items {
-		if item.Size == AutoSize {
-			auto += 1
+// SetTheme sets the theme of the layout and all its items, overriding the
+// theme of the window manager. When a theme is set, the background color and
+// splitter style of the layout are taken from the theme.
+func (l *Layout) SetTheme(theme *Theme) *Layout {
+	l.theme = theme
+	return l
+}
+
+// GetTheme returns the theme set on the layout, if any
+func (l *Layout) GetTheme() *Theme {
+	return l.theme
+}
+
+// currentTheme returns the theme of the layout, the inherited one or nil
+func (l *Layout) currentTheme() *Theme {
+	if l.theme != nil {
+		return l.theme
+	}
+	return l.inheritedTheme
+}
+
+// splitterStyles returns the background color and the normal, focused and
+// dragging splitter styles
+func (l *Layout) splitterStyles() (tcell.Color, tcell.Style, tcell.Style, tcell.Style) {
+	if theme := l.currentTheme(); theme != nil {
+		return theme.Background, theme.Splitter, theme.SplitterFocused, theme.SplitterDragging
+	}
+	return l.backgroundColor, l.splitterStyle, l.splitterStyle, l.splitterStyle
+}
+
+// itemSizes returns the size of every item along the layout direction
+func (l *Layout) itemSizes() []int {
+	space := l.availableSpace() - l.splittersAmount()*l.separatorWidth()
+	sizes := make([]int, len(l.items))
+
+	free, weights := space, 0
+	for i, item := range l.items {
+		switch {
+		case item.Unit == PercentUnit:
+			sizes[i] = space * item.Size / 100
+		case item.weight() == 0:
+			sizes[i] = item.Size
+		default:
+			weights += item.weight()
+		}
+		free -= sizes[i]
+	}
+
+	if free < 0 {
+		free = 0
+	}
+
+	if weights != 0 {
+		for i, item := range l.items {
+			if weight := item.weight(); weight != 0 {
+				sizes[i] = free * weight / weights
+			}
 		}
 	}
 
-	return len(l.items) - auto, auto
+	return sizes
 }
 
-func (l *Layout) itemsSize() int {
-	size := 0
-	for _, item := range l.items {
-		size += item.Size
+// contentRect returns the area of the layout available to its items, inside
+// the frame if the borders are collapsed or inside the padding otherwise
+func (l *Layout) contentRect() (int, int, int, int) {
+	x, y, width, height := l.GetRect()
+
+	inset := l.padding
+	if l.singleWindow {
+		inset = 0
+	}
+	if l.isCollapsed() {
+		inset = 1
+	}
+
+	x, y, width, height = x+inset, y+inset, width-2*inset, height-2*inset
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
 	}
 
-	return size
+	return x, y, width, height
 }
 
 func (l *Layout) availableSpace() int {
+	_, _, width, height := l.contentRect()
 	switch l.direction {
 	case HorizontalLayout:
-		return l.width
+		return width
 	case VerticalLayout:
-		return l.height
+		return height
 	default:
 		return 0
 	}
 }
 
+// itemSize returns the current size of the item along the layout direction
+func (l *Layout) itemSize(item *Item) int {
+	sizes := l.itemSizes()
+	for i := range l.items {
+		if l.items[i] == item {
+			return sizes[i]
+		}
+	}
+	return 0
+}
+
 func (l *Layout) splittersAmount() int {
 	if len(l.items) <= 1 {
 		return 0
@@ -181,10 +399,18 @@ func (l *Layout) splittersAmount() int {
 func (l *Layout) Draw(screen tcell.Screen) {
 	x, y, width, height := l.GetRect()
 	def := tcell.StyleDefault
+	backgroundColor, splitterStyle, focusedStyle, draggingStyle := l.splitterStyles()
+	borders := orDefaultBorders(l.inheritedBorders)
+
+	sizes := l.itemSizes()
+
+	// with damage tracking an unchanged layout is still on the screen, only
+	// its items are drawn
+	repaint := l.repaint(sizes)
 
 	// Fill background.
-	background := def.Background(l.backgroundColor)
-	if l.backgroundColor != tcell.ColorDefault {
+	background := def.Background(backgroundColor)
+	if repaint && backgroundColor != tcell.ColorDefault {
 		for y_ := y; y_ < y+height; y_++ {
 			for x_ := x; x_ < x+width; x_++ {
 				screen.SetContent(x_, y_, ' ', nil, background)
@@ -192,77 +418,75 @@ func (l *Layout) Draw(screen tcell.Screen) {
 		}
 	}
 
-	_, auto := l.itemsAmount()
-	size := l.itemsSize()
-	space := l.availableSpace()
-	seps := l.splittersAmount()
+	collapsed := l.isCollapsed()
+	if repaint && collapsed {
+		l.drawFrame(screen, borders, splitterStyle)
+	}
 
-	autoSize := 0
-	if auto != 0 {
-		autoSize = (space - size - seps) / auto
+	x, y, width, height = l.contentRect()
+	seps := l.splittersAmount()
+	separator := l.separatorWidth()
+
+	// the focused splitter is drawn with the focus glyph
+	splitterLook := func(number int, normal, focus rune) (rune, tcell.Style) {
+		switch {
+		case number != l.focusedSplitterNumber:
+			return normal, splitterStyle
+		case l.draggedSplitter != nil:
+			return focus, draggingStyle
+		default:
+			return focus, focusedStyle
+		}
 	}
 
 	switch l.direction {
 	case HorizontalLayout:
-		vertical := tview.Borders.Vertical
-
 		for number, item := range l.items {
-			if item.Size == AutoSize {
-				item.Primitive.SetRect(x, y, autoSize, height)
-				item.Primitive.Draw(NewClipRegion(screen, x, y, autoSize, height))
-				x += autoSize
-			} else {
-				item.Primitive.SetRect(x, y, item.Size, height)
-				item.Primitive.Draw(NewClipRegion(screen, x, y, item.Size, height))
-				x += item.Size
-			}
+			ix, iy, iw, ih := l.placeItem(item.Primitive, x, y, sizes[number], height)
+			item.Primitive.SetRect(ix, iy, iw, ih)
+			expose(item.Primitive, repaint)
+			item.Primitive.Draw(NewClipRegion(screen, ix, iy, iw, ih))
+			x += sizes[number]
 
 			if seps > 0 {
-				if l.splitterFlag {
-					if number == l.focusedSplitterNumber {
-						vertical = tview.Borders.VerticalFocus
-					}
-
+				if repaint && (l.splitterFlag || collapsed) {
+					vertical, style := splitterLook(number, borders.Vertical, borders.VerticalFocus)
 					for y_ := y; y_ < y+height; y_++ {
-						screen.SetContent(x, y_, vertical, nil, l.splitterStyle)
+						screen.SetContent(x+(separator-1)/2, y_, vertical, nil, style)
 					}
 				}
 
 				seps -= 1
-				x += 1
+				x += separator
 			}
 		}
 
 	case VerticalLayout:
-		horizontal := tview.Borders.Horizontal
-
 		for number, item := range l.items {
-			if item.Size == AutoSize {
-				item.Primitive.SetRect(x, y, width, autoSize)
-				item.Primitive.Draw(NewClipRegion(screen, x, y, width, autoSize))
-				y += autoSize
-			} else {
-				item.Primitive.SetRect(x, y, width, item.Size)
-				item.Primitive.Draw(NewClipRegion(screen, x, y, width, item.Size))
-				y += item.Size
-			}
+			ix, iy, iw, ih := l.placeItem(item.Primitive, x, y, width, sizes[number])
+			item.Primitive.SetRect(ix, iy, iw, ih)
+			expose(item.Primitive, repaint)
+			item.Primitive.Draw(NewClipRegion(screen, ix, iy, iw, ih))
+			y += sizes[number]
 
 			if seps > 0 {
-				if l.splitterFlag {
-					if number == l.focusedSplitterNumber {
-						horizontal = tview.Borders.HorizontalFocus
-					}
-
+				if repaint && (l.splitterFlag || collapsed) {
+					horizontal, style := splitterLook(number, borders.Horizontal, borders.HorizontalFocus)
 					for x_ := x; x_ < x+width; x_++ {
-						screen.SetContent(x_, y, horizontal, nil, l.splitterStyle)
+						screen.SetContent(x_, y+(separator-1)/2, horizontal, nil, style)
 					}
 				}
 
 				seps -= 1
-				y += 1
+				y += separator
 			}
 		}
 	}
+
+	// the items may have drawn their borders over the junctions
+	if collapsed {
+		l.drawJunctions(screen, borders, splitterStyle)
+	}
 }
 
 func (l *Layout) GetRect() (int, int, int, int) {
@@ -372,17 +596,25 @@ func (l *Layout) MouseHandler() func(action tview.MouseAction, event *tcell.Even
 				l.dragX = x
 				l.dragY = y
 
+				// the size of the items may differ from their rectangles when
+				// the borders are collapsed
+				sizeA, sizeB := l.itemSize(l.draggedSplitter.a), l.itemSize(l.draggedSplitter.b)
+
 				switch l.direction {
 				case HorizontalLayout:
 					l.draggedSplitter.a.SetRect(wxa, wya, wwa+dx, wha)
 					l.draggedSplitter.b.SetRect(wxb+dx, wyb, wwb-dx, whb)
-					l.draggedSplitter.a.Size = wwa + dx
-					l.draggedSplitter.b.Size = wwb - dx
+					l.draggedSplitter.a.Size = sizeA + dx
+					l.draggedSplitter.b.Size = sizeB - dx
+					l.draggedSplitter.a.Unit = FixedUnit
+					l.draggedSplitter.b.Unit = FixedUnit
 				case VerticalLayout:
 					l.draggedSplitter.a.SetRect(wxa, wya, wwa, wha+dy)
 					l.draggedSplitter.b.SetRect(wxb, wyb+dy, wwb, whb-dy)
-					l.draggedSplitter.a.Size = wha + dy
-					l.draggedSplitter.b.Size = whb - dy
+					l.draggedSplitter.a.Size = sizeA + dy
+					l.draggedSplitter.b.Size = sizeB - dy
+					l.draggedSplitter.a.Unit = FixedUnit
+					l.draggedSplitter.b.Unit = FixedUnit
 				default:
 					panic(fmt.Sprintf("invalid layout direction: %v", l.direction))
 				}
@@ -436,9 +668,26 @@ func (l *Layout) WrapMouseHandler(mouseHandler func(tview.MouseAction, *tcell.Ev
 }
 
 func (l *Layout) AddItem(p tview.Primitive, size int) *Layout {
+	return l.addItem(p, size, FixedUnit)
+}
+
+// AddItemPercent adds an item taking the given percentage of the layout space
+func (l *Layout) AddItemPercent(p tview.Primitive, percent int) *Layout {
+	return l.addItem(p, percent, PercentUnit)
+}
+
+// AddItemWeight adds an item sharing the free space with the other weighted
+// and auto sized items proportionally to the given weight. An AutoSize item
+// has a weight of one.
+func (l *Layout) AddItemWeight(p tview.Primitive, weight int) *Layout {
+	return l.addItem(p, weight, WeightUnit)
+}
+
+func (l *Layout) addItem(p tview.Primitive, size int, unit SizeUnit) *Layout {
 	l.items = append(l.items, &Item{
 		Primitive: p,
 		Size:      size,
+		Unit:      unit,
 	})
 
 	l.draggedSplitter = nil
@@ -459,6 +708,20 @@ func (l *Layout) RemoveItem(i int) *Layout {
 	return l
 }
 
+// insertItem inserts the item before the i-th item, at the end if i is out of
+// range
+func (l *Layout) insertItem(i int, item *Item) {
+	if i < 0 || i > len(l.items) {
+		i = len(l.items)
+	}
+
+	l.draggedSplitter = nil
+	l.items = append(l.items, nil)
+	copy(l.items[i+1:], l.items[i:])
+	l.items[i] = item
+	l.rebuildSplitters()
+}
+
 func (l *Layout) GetItem(i int) *Item {
 	if i < 0 || i >= len(l.items) {
 		return nil
@@ -467,6 +730,21 @@ func (l *Layout) GetItem(i int) *Item {
 	return l.items[i]
 }
 
+// GetSplitterPosition returns a screen position on the line of the i-th
+// splitter, between the items i and i+1, as laid out by the last draw. It
+// returns false if there is no such splitter.
+func (l *Layout) GetSplitterPosition(i int) (int, int, bool) {
+	if i < 0 || i >= len(l.splitters) {
+		return 0, 0, false
+	}
+
+	s := l.splitters[i]
+	if s.vertical {
+		return s.line(), (s.y[0] + s.y[1]) / 2, true
+	}
+	return (s.x[0] + s.x[1]) / 2, s.line(), true
+}
+
 func (l *Layout) CountItems() int {
 	return len(l.items)
 }
@@ -524,58 +802,45 @@ func (l *Layout) GetMouseCapture() func(action tview.MouseAction, event *tcell.E
 func (l *Layout) rebuildSplitters() {
 	l.splitters = nil
 
-	x, y, width, height := l.GetRect()
+	x, y, width, height := l.contentRect()
 
-	_, auto := l.itemsAmount()
-	size := l.itemsSize()
-	space := l.availableSpace()
+	sizes := l.itemSizes()
 	seps := l.splittersAmount()
-
-	autoSize := 0
-	if auto != 0 {
-		autoSize = (space - size - seps) / auto
-	}
+	separator := l.separatorWidth()
 
 	switch l.direction {
 	case HorizontalLayout:
 		for i := 0; i < len(l.items)-1; i++ {
-			if l.items[i].Size == AutoSize {
-				x += autoSize
-			} else {
-				x += l.items[i].Size
-			}
+			x += sizes[i]
 
 			if seps > 0 {
 				l.splitters = append(l.splitters, &splitter{
-					x: [2]int{x, x},
-					y: [2]int{y, y + height - 1},
-					a: l.items[i],
-					b: l.items[i+1],
+					x:        [2]int{x, x + separator - 1},
+					y:        [2]int{y, y + height - 1},
+					vertical: true,
+					a:        l.items[i],
+					b:        l.items[i+1],
 				})
 
 				seps -= 1
-				x += 1
+				x += separator
 			}
 		}
 
 	case VerticalLayout:
 		for i := 0; i < len(l.items)-1; i++ {
-			if l.items[i].Size == AutoSize {
-				y += autoSize
-			} else {
-				y += l.items[i].Size
-			}
+			y += sizes[i]
 
 			if seps > 0 {
 				l.splitters = append(l.splitters, &splitter{
 					x: [2]int{x, x + width - 1},
-					y: [2]int{y, y},
+					y: [2]int{y, y + separator - 1},
 					a: l.items[i],
 					b: l.items[i+1],
 				})
 
 				seps -= 1
-				y += 1
+				y += separator
 			}
 		}
 	}
@@ -0,0 +1,56 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestGapsAndPadding(t *testing.T) {
+	type rect struct{ x, y, width, height int }
+
+	tests := []struct {
+		name             string
+		gap, padding     int
+		layoutGap        int
+		layoutPadding    int
+		single           bool
+		left, right      rect
+		splitterPosition int
+	}{
+		{name: "no gaps", layoutGap: -1, left: rect{0, 0, 20, 10}, right: rect{21, 0, 20, 10}, splitterPosition: 20},
+		{name: "manager", gap: 2, padding: 1, layoutGap: -1, left: rect{1, 1, 18, 8}, right: rect{22, 1, 18, 8}, splitterPosition: 20},
+		{name: "layout padding", gap: 2, padding: 1, layoutGap: -1, layoutPadding: 2, left: rect{3, 3, 16, 4}, right: rect{22, 3, 16, 4}, splitterPosition: 20},
+		{name: "layout gap", gap: 2, layoutGap: 4, left: rect{0, 0, 18, 10}, right: rect{23, 0, 18, 10}, splitterPosition: 20},
+		{name: "single window", gap: 2, padding: 1, layoutGap: 4, layoutPadding: 2, single: true, left: rect{0, 0, 20, 10}, splitterPosition: 20},
+	}
+
+	for _, test := range tests {
+		// the right item is not a window, it counts as a visible tile all the same
+		left, right := NewWindow().SetRoot(tview.NewBox()), tview.NewBox()
+		root := NewLayout().SetDirection(HorizontalLayout).SetSplitter(true).
+			SetGap(test.layoutGap).
+			SetPadding(test.layoutPadding).
+			AddItemWeight(left, 1)
+		if test.single {
+			// an empty layout is no tile
+			root.AddItemWeight(NewLayout(), 1)
+		} else {
+			root.AddItemWeight(right, 1)
+		}
+
+		m := NewWindowManager().SetRoot(root).SetGaps(test.gap, test.padding)
+		m.SetRect(0, 0, 41, 10)
+		m.Draw(newSimulationScreen(t, 41, 10))
+
+		if x, y, width, height := left.GetRect(); (rect{x, y, width, height}) != test.left {
+			t.Errorf("%s: the left item is at %v, want %v", test.name, rect{x, y, width, height}, test.left)
+		}
+		if x, y, width, height := right.GetRect(); !test.single && (rect{x, y, width, height}) != test.right {
+			t.Errorf("%s: the right item is at %v, want %v", test.name, rect{x, y, width, height}, test.right)
+		}
+		if x, _, _ := root.GetSplitterPosition(0); x != test.splitterPosition {
+			t.Errorf("%s: the splitter is at %d, want %d", test.name, x, test.splitterPosition)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package tilman
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/tview"
+)
+
+// ParseError describes a syntax or binding error in a layout description
+type ParseError struct {
+	Line, Column int // position of the error, both starting at 1
+	Message      string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ParseLayout builds a layout tree from a compact text description and binds
+// the given primitives to the named slots of the description, e.g.
+//
+//   h|[ v[logs:30%, shell], editor:2* ]
+//
+// A layout is written as its direction, 'h' (horizontal) or 'v' (vertical),
+// optionally followed by '|' to draw splitters, and the comma separated list of
+// its items in brackets. An item is either a nested layout or the name of a
+// slot, optionally followed by a colon and its size:
+//
+//   name      auto size
+//   name:20   20 cells
+//   name:30%  30 percent of the layout space
+//   name:2*   weight of 2 when sharing the free space with auto sized items
+//
+// Sizes must be greater than zero and the percentages of the items of a layout
+// may add up to at most 100.
+// Whitespace is ignored and '#' starts a comment lasting to the end of the line.
+// Every slot must be bound to a primitive and may only be used once.
+func ParseLayout(text string, slots map[string]tview.Primitive) (*Layout, error) {
+	return parseLayout(text, slots, true)
+}
+
+// parseLayout parses a layout description, if bind is false, slots are not
+// looked up and empty boxes are used in their place
+func parseLayout(text string, slots map[string]tview.Primitive, bind bool) (*Layout, error) {
+	p := &layoutParser{
+		text:  []rune(text),
+		line:  1,
+		col:   1,
+		slots: slots,
+		bind:  bind,
+		used:  make(map[string]bool),
+	}
+
+	p.skipSpace()
+	line, col := p.line, p.col
+	name := p.ident()
+	if name != "h" && name != "v" {
+		return nil, &ParseError{line, col, "expected layout direction 'h' or 'v'"}
+	}
+
+	layout, err := p.layout(name)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected %s after layout", p.describe())
+	}
+
+	return layout, nil
+}
+
+type layoutParser struct {
+	text      []rune
+	pos       int
+	line, col int
+
+	slots map[string]tview.Primitive
+	bind  bool
+	used  map[string]bool
+}
+
+func (p *layoutParser) eof() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *layoutParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.text[p.pos]
+}
+
+func (p *layoutParser) next() rune {
+	r := p.peek()
+	p.pos++
+	if r == '\n' {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+	return r
+}
+
+func (p *layoutParser) skipSpace() {
+	for !p.eof() {
+		switch r := p.peek(); {
+		case r == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		case unicode.IsSpace(r):
+			p.next()
+		default:
+			return
+		}
+	}
+}
+
+// describe returns a human readable description of the current character
+func (p *layoutParser) describe() string {
+	if p.eof() {
+		return "end of input"
+	}
+	return strconv.QuoteRune(p.peek())
+}
+
+func (p *layoutParser) errorf(format string, args ...interface{}) error {
+	return &ParseError{p.line, p.col, fmt.Sprintf(format, args...)}
+}
+
+func isIdentRune(r rune, first bool) bool {
+	if r == '_' || unicode.IsLetter(r) {
+		return true
+	}
+	return !first && (r == '-' || r == '.' || unicode.IsDigit(r))
+}
+
+func (p *layoutParser) ident() string {
+	var b strings.Builder
+	for !p.eof() && isIdentRune(p.peek(), b.Len() == 0) {
+		b.WriteRune(p.next())
+	}
+	return b.String()
+}
+
+func (p *layoutParser) expect(r rune) error {
+	p.skipSpace()
+	if p.eof() || p.peek() != r {
+		return p.errorf("expected %q, found %s", r, p.describe())
+	}
+	p.next()
+	return nil
+}
+
+// layout parses the rest of a layout after its direction
+func (p *layoutParser) layout(direction string) (*Layout, error) {
+	layout := NewLayout()
+	if direction == "h" {
+		layout.SetDirection(HorizontalLayout)
+	} else {
+		layout.SetDirection(VerticalLayout)
+	}
+
+	p.skipSpace()
+	if p.peek() == '|' {
+		p.next()
+		layout.SetSplitter(true)
+	}
+
+	if err := p.expect('['); err != nil {
+		return nil, err
+	}
+
+	percent := 0
+	for {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.next()
+			return layout, nil
+		}
+
+		if err := p.item(layout, &percent); err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']', found %s", p.describe())
+		}
+	}
+}
+
+// item parses a slot or a nested layout with its optional size and adds it to
+// the layout, percent is the sum of the percentages of the previous items
+func (p *layoutParser) item(layout *Layout, percent *int) error {
+	line, col := p.line, p.col
+	name := p.ident()
+	if name == "" {
+		return p.errorf("expected slot name or layout, found %s", p.describe())
+	}
+
+	var primitive tview.Primitive
+
+	p.skipSpace()
+	if (name == "h" || name == "v") && (p.peek() == '[' || p.peek() == '|') {
+		nested, err := p.layout(name)
+		if err != nil {
+			return err
+		}
+		primitive = nested
+	} else {
+		if p.used[name] {
+			return &ParseError{line, col, fmt.Sprintf("slot %q is used more than once", name)}
+		}
+		p.used[name] = true
+
+		if !p.bind {
+			primitive = tview.NewBox()
+		} else if primitive = p.slots[name]; primitive == nil {
+			return &ParseError{line, col, fmt.Sprintf("no primitive bound to slot %q", name)}
+		}
+	}
+
+	p.skipSpace()
+	if p.peek() != ':' {
+		layout.AddItem(primitive, AutoSize)
+		return nil
+	}
+	p.next()
+	p.skipSpace()
+
+	line, col = p.line, p.col
+	digits := ""
+	for !p.eof() && '0' <= p.peek() && p.peek() <= '9' {
+		digits += string(p.next())
+	}
+	if digits == "" {
+		return p.errorf("expected size, found %s", p.describe())
+	}
+
+	size, err := strconv.Atoi(digits)
+	if err != nil {
+		return &ParseError{line, col, fmt.Sprintf("invalid size %q", digits)}
+	}
+	if size == 0 {
+		return &ParseError{line, col, "size must be greater than zero"}
+	}
+
+	switch p.peek() {
+	case '%':
+		p.next()
+		if size > 100 {
+			return &ParseError{line, col, fmt.Sprintf("percentage %d%% is greater than 100%%", size)}
+		}
+		if *percent += size; *percent > 100 {
+			return &ParseError{line, col, fmt.Sprintf("percentages add up to %d%%, more than 100%%", *percent)}
+		}
+		layout.AddItemPercent(primitive, size)
+	case '*':
+		p.next()
+		layout.AddItemWeight(primitive, size)
+	default:
+		layout.AddItem(primitive, size)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/rivo/tview"
+)
+
+func TestParseLayout(t *testing.T) {
+	slots := map[string]tview.Primitive{
+		"logs":   tview.NewBox(),
+		"shell":  tview.NewBox(),
+		"editor": tview.NewBox(),
+	}
+
+	layout, err := ParseLayout("h|[ v[logs:30%, shell:5], # comment\n editor:2* ]", slots)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.GetDirection() != HorizontalLayout || layout.CountItems() != 2 {
+		t.Fatalf("the root has direction %d and %d items", layout.GetDirection(), layout.CountItems())
+	}
+	nested, ok := layout.GetItem(0).Primitive.(*Layout)
+	if !ok || nested.GetDirection() != VerticalLayout || nested.CountItems() != 2 {
+		t.Fatalf("the first item is %T, want a vertical layout with 2 items", layout.GetItem(0).Primitive)
+	}
+
+	tests := []struct {
+		item      *Item
+		primitive tview.Primitive
+		size      int
+		unit      SizeUnit
+	}{
+		{nested.GetItem(0), slots["logs"], 30, PercentUnit},
+		{nested.GetItem(1), slots["shell"], 5, FixedUnit},
+		{layout.GetItem(1), slots["editor"], 2, WeightUnit},
+	}
+	for i, test := range tests {
+		if test.item.Primitive != test.primitive || test.item.Size != test.size || test.item.Unit != test.unit {
+			t.Errorf("item %d has size %d and unit %d, want %d and %d",
+				i, test.item.Size, test.item.Unit, test.size, test.unit)
+		}
+	}
+
+	// the percentages only add up within a layout
+	if _, err := ParseLayout("h[logs:60%, v[shell:60%, editor:40%]]", slots); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	slots := map[string]tview.Primitive{
+		"a": tview.NewBox(),
+		"b": tview.NewBox(),
+		"c": tview.NewBox(),
+	}
+
+	tests := []struct {
+		text         string
+		line, column int
+	}{
+		{"x[a]", 1, 1},
+		{"h a", 1, 3},
+		{"h[a b]", 1, 5},
+		{"h[a,", 1, 5},
+		{"h[a, ,]", 1, 6},
+		{"h[a, a]", 1, 6},
+		{"h[a, d]", 1, 6},
+		{"h[a:]", 1, 5},
+		{"h[a:x]", 1, 5},
+		{"h[a:0]", 1, 5},
+		{"h[a:0*]", 1, 5},
+		{"h[a:0%]", 1, 5},
+		{"h[a:101%]", 1, 5},
+		{"h[a:٣]", 1, 5},
+		{"h[\n  a:60%,\n  b:50%,\n  c\n]", 3, 5},
+		{"h[a] b", 1, 6},
+		{"h[\n  a, # comment\n  b:99999999999999999999\n]", 3, 5},
+	}
+
+	for _, test := range tests {
+		_, err := ParseLayout(test.text, slots)
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%q returned %v, want a parse error", test.text, err)
+			continue
+		}
+		if parseErr.Line != test.line || parseErr.Column != test.column {
+			t.Errorf("%q failed at line %d, column %d, want line %d, column %d: %v",
+				test.text, parseErr.Line, parseErr.Column, test.line, test.column, err)
+		}
+	}
+}
@@ -7,6 +7,44 @@ import (
 	"github.com/rivo/tview"
 )
 
+// Action is a window manager command which can be bound to a key
+type Action string
+
+const (
+	ActionFocusNext      Action = "focus-next"      // focus the next visible window
+	ActionFocusPrevious  Action = "focus-previous"  // focus the previous visible window
+	ActionToggleMaximize Action = "toggle-maximize" // maximize or restore the focused window
+	ActionFocusTitleBar  Action = "focus-title-bar" // select the buttons of the focused window
+	ActionWindowMenu     Action = "window-menu"     // open the context menu of the focused window
+	ActionFocusUrgent    Action = "focus-urgent"    // focus the window requiring attention the longest
+	ActionCapture        Action = "capture"         // write the rendering of the manager to a file
+)
+
+// Actions lists all window manager actions
+var Actions = []Action{
+	ActionFocusNext,
+	ActionFocusPrevious,
+	ActionToggleMaximize,
+	ActionFocusTitleBar,
+	ActionWindowMenu,
+	ActionFocusUrgent,
+	ActionCapture,
+}
+
+// Manager shows a tree of layouts and windows and lets the user focus,
+// maximize and arrange them.
+//
+// The manager is locked while it draws and handles key and mouse events, so
+// the callbacks it invokes from there, such as window button callbacks, run
+// with the lock held and may change the tree directly. Other goroutines have
+// to change the tree inside Update, which waits for the lock. The menu items
+// added by the application are invoked without the lock.
+//
+// The methods which lock the manager must not be called from the callbacks
+// holding the lock nor inside Update, which would deadlock: Update, Draw,
+// Focus, HasFocus, the handlers, Capture, CaptureWindow, CaptureToFile,
+// Config.Apply, Session.Save and Session.Restore. Such a callback may call
+// them in a new goroutine, which waits for the lock.
 type Manager struct {
 	*tview.Box
 	sync.Mutex
@@ -15,6 +53,65 @@ type Manager struct {
 	visibleRoot tview.Primitive
 
 	restoreX, restoreY, restoreWidth, restoreHeight int
+
+	// primitive to receive focus the next time the manager is focused
+	pendingFocus tview.Primitive
+
+	keybindings map[Action]KeyBinding
+
+	theme *Theme
+
+	// glyphs of the splitters and collapsed borders, tview.Borders if nil
+	borders *BorderSet
+
+	// gap between the items of the layouts and padding around the root
+	gap, padding int
+
+	// the open context menu of a window, if any
+	menu *contextMenu
+	// creates the window placed next to the window split with the menu
+	split func(w *Window) *Window
+
+	// called when the urgent windows change and the windows it was last
+	// called with
+	urgentFunc     func(windows []*Window)
+	notifiedUrgent []*Window
+
+	// how the windows without focus are drawn
+	dimMode DimMode
+
+	// primitives drawn over the layouts, from the bottom to the top, and the
+	// layer which had focus when the manager was last drawn
+	layers     []*Layer
+	focusLayer *Layer
+
+	// whether windows are drawn from their cache while they do not change
+	damageTracking bool
+
+	// where and how the capture action writes the rendering
+	captureDir     string
+	captureFormat  CaptureFormat
+	captureOnError func(err error)
+
+	// records the events reaching the manager, if any
+	recorder *Recorder
+	// the primitive which captured the mouse, the manager captures it in its
+	// place and forwards the events, so that they are all recorded
+	mouseCapture tview.Primitive
+
+	// the frame drawn last and whether the next draw draws all cells, with
+	// damage tracking
+	drawnFrame  frameState
+	drawnEdges  []edgeCell
+	invalidated bool
+
+	// called after the events and updates which may have changed the manager
+	observersMutex sync.Mutex
+	observers      []*observer
+}
+
+type observer struct {
+	changed func()
 }
 
 func NewWindowManager() *Manager {
@@ -25,11 +122,25 @@ func NewWindowManager() *Manager {
 		Box:         tview.NewBox(),
 		logicalRoot: layout,
 		visibleRoot: layout,
+		keybindings: make(map[Action]KeyBinding),
 	}
 
 	return manager
 }
 
+// Update runs the function with the manager locked, so that it can add,
+// remove and change layouts and windows while the manager is drawn and
+// handles events in another goroutine. The screen is not redrawn, usually
+// Application.Draw is called afterwards.
+func (m *Manager) Update(f func()) *Manager {
+	defer m.notifyObservers()
+	m.Lock()
+	defer m.Unlock()
+
+	f()
+	return m
+}
+
 func (m *Manager) SetRoot(root *Layout) *Manager {
 	m.logicalRoot = root
 	m.visibleRoot = root
@@ -64,17 +175,228 @@ func (m *Manager) Restore() *Manager {
 	return m
 }
 
+// SetTheme sets the theme of the manager and all the layouts and windows it
+// contains, except those with a theme of their own. A nil theme leaves the
+// primitives with their own colors.
+func (m *Manager) SetTheme(theme *Theme) *Manager {
+	m.theme = theme
+	if theme != nil {
+		fg, _, _ := theme.Border.Decompose()
+		titleFg, _, _ := theme.Title.Decompose()
+		m.Box.SetBackgroundColor(theme.Background)
+		m.Box.SetBorderColor(fg)
+		m.Box.SetTitleColor(titleFg)
+	}
+
+	return m
+}
+
+// GetTheme returns the theme of the manager
+func (m *Manager) GetTheme() *Theme {
+	return m.theme
+}
+
+// SetBorderSet sets the glyphs the layouts draw splitters and collapsed
+// borders with, and windows without border style their title bar. A nil set
+// uses tview.Borders.
+func (m *Manager) SetBorderSet(set *BorderSet) *Manager {
+	m.borders = set
+	return m
+}
+
+// GetBorderSet returns the glyphs set on the manager, if any
+func (m *Manager) GetBorderSet() *BorderSet {
+	return m.borders
+}
+
+// SetGaps sets the number of empty cells between the items of all layouts
+// without a gap of their own (see Layout.SetGap) and around the root layout.
+// Gaps are disabled while only one window is visible.
+func (m *Manager) SetGaps(gap, padding int) *Manager {
+	m.gap = gap
+	m.padding = padding
+	return m
+}
+
+// GetGaps returns the gap between layout items and the padding around the root
+func (m *Manager) GetGaps() (int, int) {
+	return m.gap, m.padding
+}
+
+// SetKeybinding binds the action to the given key, replacing its previous key.
+// A zero key binding removes the binding of the action.
+func (m *Manager) SetKeybinding(action Action, key KeyBinding) *Manager {
+	if key.IsZero() {
+		delete(m.keybindings, action)
+	} else {
+		m.keybindings[action] = key
+	}
+
+	return m
+}
+
+// GetKeybinding returns the key bound to the action, if any
+func (m *Manager) GetKeybinding(action Action) (KeyBinding, bool) {
+	key, ok := m.keybindings[action]
+	return key, ok
+}
+
+// FocusedWindow returns the visible window which has focus, if any
+func (m *Manager) FocusedWindow() *Window {
+	if layer := m.focusedLayer(); layer != nil {
+		w, _ := layer.Primitive.(*Window)
+		return w
+	}
+
+	for _, w := range collectWindows(m.visibleRoot) {
+		if w.HasFocus() {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// perform executes the action, it returns false if the action was not applicable
+func (m *Manager) perform(action Action, setFocus func(p tview.Primitive)) bool {
+	switch action {
+	case ActionFocusNext, ActionFocusPrevious:
+		windows := collectWindows(m.visibleRoot)
+		if len(windows) == 0 {
+			return false
+		}
+
+		current := -1
+		for i, w := range windows {
+			if w.HasFocus() {
+				current = i
+				break
+			}
+		}
+
+		next := 0
+		if action == ActionFocusNext {
+			next = (current + 1) % len(windows)
+		} else if current > 0 {
+			next = current - 1
+		} else {
+			next = len(windows) - 1
+		}
+		setFocus(windows[next])
+
+	case ActionToggleMaximize:
+		w := m.FocusedWindow()
+		if w == nil {
+			return false
+		}
+
+		if m.IsMaximazed(w) {
+			m.Restore()
+		} else {
+			m.Maximize(w)
+		}
+		setFocus(w)
+
+	case ActionFocusTitleBar:
+		w := m.FocusedWindow()
+		if w == nil {
+			return false
+		}
+		return w.focusTitleBar()
+
+	case ActionWindowMenu:
+		w := m.FocusedWindow()
+		if w == nil {
+			return false
+		}
+		x, y, _, _ := w.GetRect()
+		if layer := m.windowLayer(w); layer != nil {
+			x, y = layer.X, layer.Y
+		}
+		m.openMenu(w, x, y, setFocus)
+
+	case ActionCapture:
+		if _, err := m.captureToFile(m.captureDir, m.captureFormat); err != nil && m.captureOnError != nil {
+			m.captureOnError(err)
+		}
+
+	case ActionFocusUrgent:
+		if m.focusUrgent(setFocus) == nil {
+			return false
+		}
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// countLeaves returns the number of primitives in the tree which are not
+// layouts, such as windows
+func countLeaves(p tview.Primitive) int {
+	l, ok := p.(*Layout)
+	if !ok {
+		return 1
+	}
+
+	leaves := 0
+	for _, item := range l.items {
+		leaves += countLeaves(item.Primitive)
+	}
+	return leaves
+}
+
+// collectWindows returns all windows of the primitive tree in layout order
+func collectWindows(p tview.Primitive) []*Window {
+	switch p := p.(type) {
+	case *Window:
+		return []*Window{p}
+	case *Layout:
+		var windows []*Window
+		for _, item := range p.items {
+			windows = append(windows, collectWindows(item.Primitive)...)
+		}
+		return windows
+	default:
+		return nil
+	}
+}
+
 // Focus is called when this primitive receives focus
 func (m *Manager) Focus(delegate func(p tview.Primitive)) {
 	m.Lock()
 	defer m.Unlock()
 
+	if m.pendingFocus != nil {
+		p := m.pendingFocus
+		m.pendingFocus = nil
+		delegate(p)
+		return
+	}
+
+	for _, layer := range m.layers {
+		if layer == m.focusLayer {
+			delegate(layer.Primitive)
+			return
+		}
+	}
+
 	m.visibleRoot.Focus(delegate)
 }
 
-// HasFocus returns whether or not this primitive has focus.
+// HasFocus returns whether or not this primitive has focus. It locks the
+// manager, see Manager.
 func (m *Manager) HasFocus() bool {
-	return m.visibleRoot.HasFocus()
+	m.Lock()
+	defer m.Unlock()
+
+	return m.hasFocus()
+}
+
+// hasFocus returns whether the manager has focus, the caller holds the lock
+func (m *Manager) hasFocus() bool {
+	return m.visibleRoot.HasFocus() || m.focusedLayer() != nil
 }
 
 // Draw draws this primitive onto the screen.
@@ -82,34 +404,223 @@ func (m *Manager) Draw(screen tcell.Screen) {
 	m.Lock()
 	defer m.Unlock()
 
-	m.Box.Draw(screen)
+	if m.recorder != nil {
+		_, _, width, height := m.GetRect()
+		m.recorder.recordSize(width, height)
+	}
+
+	// the focus goes back to the layer the next time the manager is focused
+	if m.hasFocus() {
+		m.focusLayer = m.focusedLayer()
+	}
+
+	m.draw(screen)
+	m.notifyUrgent()
+}
+
+// draw draws the manager, the caller holds the lock
+func (m *Manager) draw(screen tcell.Screen) {
+	background := tview.Styles.PrimitiveBackgroundColor
+	if m.theme != nil {
+		background = m.theme.Background
+	}
+	dim := dimTransform(m.dimMode, background)
+
+	// the layers are styled like the layouts
+	for _, p := range append([]tview.Primitive{m.logicalRoot}, m.layerPrimitives()...) {
+		inheritTheme(p, m.theme)
+		inheritBorders(p, m.borders)
+		inheritDim(p, dim)
+		inheritDamageTracking(p, m.damageTracking)
+	}
+
+	// a maximized window is not part of a collapsed layout
+	if w, ok := m.visibleRoot.(*Window); ok {
+		w.collapsed = false
+	}
 
 	x, y, width, height := m.Box.GetInnerRect()
+	innerX, innerY, innerWidth, innerHeight := x, y, width, height
+
+	// gaps and padding, also those set on the layouts, are disabled while
+	// only one window is visible
+	gap, padding := m.gap, m.padding
+	singleWindow := countLeaves(m.visibleRoot) <= 1
+	if singleWindow {
+		gap, padding = 0, 0
+	}
+	inheritGap(m.logicalRoot, gap, singleWindow)
+
+	// with damage tracking an unchanged frame is still on the screen, only
+	// the windows which changed are drawn
+	repaint := m.repaint(screen, singleWindow)
+	if repaint {
+		m.Box.Draw(screen)
+	}
+
+	if width > 2*padding && height > 2*padding {
+		x, y, width, height = x+padding, y+padding, width-2*padding, height-2*padding
+	}
+
 	m.visibleRoot.SetRect(x, y, width, height)
+	expose(m.visibleRoot, repaint)
 	m.visibleRoot.Draw(NewClipRegion(screen, x, y, width, height))
+
+	layers := NewClipRegion(screen, innerX, innerY, innerWidth, innerHeight)
+	for _, layer := range m.layers {
+		layer.draw(layers)
+	}
+
+	if m.menu != nil {
+		theme := m.theme
+		if theme == nil {
+			theme = DefaultTheme()
+		}
+		m.menu.draw(screen, theme, innerX, innerY, innerWidth, innerHeight)
+	}
+
+	if m.damageTracking {
+		m.rememberEdges(screen)
+	}
 }
 
 // MouseHandler returns the mouse handler for this primitive.
 func (m *Manager) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
 	return m.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
+		defer m.notifyObservers()
 		m.Lock()
+
+		if m.recorder != nil {
+			x, y, _, _ := m.GetRect()
+			m.recorder.recordMouse(action, event, x, y)
+		}
+
+		// the open menu receives all mouse events, an item added by the
+		// application is invoked without the lock so that it may use the
+		// manager
+		if m.menu != nil {
+			m.mouseCapture = nil
+			selected := m.handleMenuMouse(action, event)
+			m.Unlock()
+			if selected != nil {
+				selected()
+			}
+			return true, nil
+		}
 		defer m.Unlock()
 
+		// the mouse button may be released outside of the window whose button
+		// it pressed, which then never sees the release
+		if action == tview.MouseLeftUp {
+			defer m.releaseButtons()
+		}
+
+		if m.mouseCapture != nil {
+			return m.forwardMouse(m.mouseCapture, action, event, setFocus)
+		}
+
 		// ignore mouse events out of the bounds of the window manager
 		if !m.InRect(event.Position()) {
 			return false, nil
 		}
 
-		return m.visibleRoot.MouseHandler()(action, event, setFocus)
+		x, y := event.Position()
+		if action == tview.MouseRightClick {
+			if w := m.titleBarAt(x, y); w != nil {
+				m.openMenu(w, x, y, setFocus)
+				return true, nil
+			}
+		}
+
+		// layers hide what is below them
+		if layer := m.layerAt(x, y); layer != nil {
+			return m.forwardMouse(&layerMouse{layer.Primitive, layer}, action, event, setFocus)
+		}
+
+		return m.forwardMouse(m.visibleRoot, action, event, setFocus)
 	})
 }
 
+// observe registers a function called after every event handled and every
+// update, without the lock of the manager. It returns a function removing it.
+func (m *Manager) observe(changed func()) func() {
+	o := &observer{changed}
+
+	m.observersMutex.Lock()
+	defer m.observersMutex.Unlock()
+	m.observers = append(m.observers, o)
+
+	return func() {
+		m.observersMutex.Lock()
+		defer m.observersMutex.Unlock()
+
+		for i, other := range m.observers {
+			if other == o {
+				m.observers = append(m.observers[:i:i], m.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// notifyObservers calls the observers, the caller does not hold the lock
+func (m *Manager) notifyObservers() {
+	m.observersMutex.Lock()
+	observers := m.observers
+	m.observersMutex.Unlock()
+
+	for _, o := range observers {
+		o.changed()
+	}
+}
+
+// forwardMouse passes the mouse event to the primitive, if it captures the
+// mouse the manager captures it
+func (m *Manager) forwardMouse(p tview.Primitive, action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (bool, tview.Primitive) {
+	consumed, capture := p.MouseHandler()(action, event, setFocus)
+
+	m.mouseCapture = capture
+	if capture != nil {
+		return consumed, m
+	}
+	return consumed, nil
+}
+
 // InputHandler returns a handler which receives key events when it has focus.
 func (m *Manager) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
 	return m.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		defer m.notifyObservers()
 		m.Lock()
+
+		if m.recorder != nil {
+			m.recorder.recordKey(event)
+		}
+
+		// the open menu receives all keys
+		if m.menu != nil {
+			selected := m.handleMenuKey(event)
+			m.Unlock()
+			if selected != nil {
+				selected()
+			}
+			return
+		}
 		defer m.Unlock()
 
+		// in the order of Actions, so that the same action wins every time
+		for _, action := range Actions {
+			if key, ok := m.keybindings[action]; ok && key.Matches(event) && m.perform(action, setFocus) {
+				return
+			}
+		}
+
+		if layer := m.focusedLayer(); layer != nil {
+			if handler := layer.Primitive.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+			return
+		}
+
 		inputHandler := m.visibleRoot.InputHandler()
 		if inputHandler != nil {
 			inputHandler(event, setFocus)
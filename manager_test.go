@@ -0,0 +1,174 @@
+package tilman
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newTestConfig parses the configuration in JSON
+func newTestConfig(t *testing.T, text string) *Config {
+	config, err := ParseConfig([]byte(text), ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// TestManagerConcurrentUse is meant to run with -race
+func TestManagerConcurrentUse(t *testing.T) {
+	dir := t.TempDir()
+	config := newTestConfig(t, `{"keys": {"capture": "Ctrl+P"}, "splitter": {"color": "red"}}`)
+
+	manager, windows := newPaneManager(true)
+	manager.SetRect(0, 0, 80, 24)
+	manager.SetCaptureOptions(dir, CaptureText, func(err error) { t.Error(err) })
+	for i, w := range windows {
+		w.SetKind("pane").SetUserData(i)
+	}
+	session := NewSession(manager, filepath.Join(dir, "session.json"))
+	session.StartAutosave(time.Millisecond, func(err error) { t.Error(err) })
+	defer session.StopAutosave()
+
+	screen := newSimulationScreen(t, 80, 24)
+	focus := &focuser{}
+	root := manager.GetRoot()
+	column := root.GetItem(0).Primitive.(*Layout)
+
+	tasks := []func(i int){
+		func(i int) { manager.Draw(screen) },
+		func(i int) {
+			keys := []tcell.Key{tcell.KeyTab, tcell.KeyCtrlP, tcell.KeyDown}
+			manager.InputHandler()(tcell.NewEventKey(keys[i%len(keys)], 0, tcell.ModNone), focus.setFocus)
+		},
+		func(i int) {
+			actions := []tview.MouseAction{tview.MouseLeftDown, tview.MouseMove, tview.MouseLeftUp, tview.MouseLeftClick}
+			event := tcell.NewEventMouse(i*7%80, i*3%24, tcell.Button1, tcell.ModNone)
+			manager.MouseHandler()(actions[i%len(actions)], event, focus.setFocus)
+		},
+		func(i int) {
+			manager.Update(func() {
+				w := windows[i%len(windows)]
+				w.SetTitle(fmt.Sprintf("pane %d", i)).SetUrgent(i%2 == 0).MarkDirty()
+			})
+		},
+		func(i int) {
+			// windows and layouts come and go while the others are drawn
+			manager.Update(func() {
+				if i%2 == 0 {
+					column.AddItemWeight(NewWindow().SetRoot(tview.NewBox()), 1)
+					root.AddItemWeight(NewLayout().AddItemWeight(NewWindow().SetRoot(tview.NewBox()), 1), 1)
+				} else {
+					column.RemoveItem(column.CountItems() - 1)
+					root.RemoveItem(root.CountItems() - 1)
+				}
+			})
+		},
+		func(i int) {
+			// the splitter after the first column is dragged back and forth
+			var x, y int
+			manager.Update(func() {
+				x, y, _, _ = windows[5].GetRect()
+			})
+			drag := func(action tview.MouseAction, x int) {
+				event := tcell.NewEventMouse(x, y+1, tcell.Button1, tcell.ModNone)
+				manager.MouseHandler()(action, event, focus.setFocus)
+			}
+			drag(tview.MouseLeftDown, x-1)
+			drag(tview.MouseMove, x-1+i%5-2)
+			drag(tview.MouseLeftUp, x-1+i%5-2)
+		},
+		func(i int) { manager.HasFocus() },
+		func(i int) { manager.Capture(CaptureANSI) },
+		func(i int) { manager.CaptureWindow(windows[i%len(windows)], CaptureHTML) },
+		func(i int) {
+			if _, err := manager.CaptureToFile(dir, CaptureText); err != nil {
+				t.Error(err)
+			}
+		},
+		func(i int) { config.Apply(manager) },
+		func(i int) {
+			if err := session.Save(); err != nil {
+				t.Error(err)
+			}
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task func(i int)) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				task(i)
+			}
+		}(task)
+	}
+
+	within(t, "concurrent use", wg.Wait)
+}
+
+func TestManagerCallbacks(t *testing.T) {
+	dir := t.TempDir()
+	config := newTestConfig(t, `{"keys": {"capture": "Ctrl+P", "focus-urgent": "Ctrl+U"}}`)
+
+	manager, windows := newPaneManager(false)
+	manager.SetRect(0, 0, 80, 24)
+	manager.SetCaptureOptions(dir, CaptureText, func(err error) { t.Error(err) })
+	config.Apply(manager)
+	session := NewSession(manager, filepath.Join(dir, "session.json"))
+
+	// the locked methods are called from a button callback in a goroutine
+	called := make(chan struct{})
+	windows[0].SetBorder(true).AddButton('x', WindowButtonAlignRight, func(w *Window, b *WindowButton) {
+		go func() {
+			manager.HasFocus()
+			manager.Capture(CaptureText)
+			manager.CaptureWindow(w, CaptureText)
+			if _, err := manager.CaptureToFile(dir, CaptureText); err != nil {
+				t.Error(err)
+			}
+			config.Apply(manager)
+			if err := session.Save(); err != nil {
+				t.Error(err)
+			}
+			close(called)
+		}()
+	})
+	windows[0].buttons[0].Key = KeyBinding{Key: tcell.KeyF2}
+	windows[5].SetUrgent(true)
+
+	screen := newSimulationScreen(t, 80, 24)
+	focus := &focuser{}
+	manager.Draw(screen)
+	focus.setFocus(windows[0])
+
+	key := func(key tcell.Key) {
+		manager.InputHandler()(tcell.NewEventKey(key, 0, tcell.ModNone), focus.setFocus)
+		manager.Draw(screen)
+	}
+
+	within(t, "capture action", func() { key(tcell.KeyCtrlP) })
+	within(t, "button callback", func() {
+		key(tcell.KeyF2)
+		<-called
+	})
+	within(t, "focus urgent action", func() { key(tcell.KeyCtrlU) })
+
+	if !windows[5].HasFocus() {
+		t.Errorf("the urgent window was not focused")
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 2 {
+		t.Errorf("%d files were written, want a capture and the session", len(files))
+	}
+}
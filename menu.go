@@ -0,0 +1,403 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// MenuItem is an entry of the context menu of a window
+type MenuItem struct {
+	Label    string
+	Disabled bool // a disabled item is drawn but cannot be selected
+
+	Selected func(w *Window) // callback to be invoked when the item is selected
+
+	// the built-in entries act with the lock of the manager held
+	action func(w *Window)
+}
+
+// contextMenu is the popup menu of a window drawn over the windows
+type contextMenu struct {
+	window   *Window
+	items    []*MenuItem
+	x, y     int
+	selected int
+}
+
+// AddMenuItem adds an entry to the context menu of the window, which is opened
+// with a right click on the title bar. The menu offers Maximize or Restore and
+// Float, Minimize in vertical layouts, Split once Manager.SetSplitFunc is set,
+// the entries of the application and Close. A floating window offers Dock
+// instead of Maximize, Float and Split. It has no Move to workspace entry, the
+// manager has no workspaces.
+func (w *Window) AddMenuItem(label string, selected func(w *Window)) *Window {
+	w.menuItems = append(w.menuItems, &MenuItem{
+		Label:    label,
+		Selected: selected,
+	})
+	return w
+}
+
+// GetMenuItems returns the entries the application added to the context menu
+func (w *Window) GetMenuItems() []*MenuItem {
+	return w.menuItems
+}
+
+// Close removes the window from the layouts or the layers of the manager, a
+// maximized window is restored first
+func (m *Manager) Close(w *Window) *Manager {
+	if m.IsMaximazed(w) {
+		m.Restore()
+	}
+	if m.menu != nil && m.menu.window == w {
+		m.menu = nil
+	}
+
+	if layer := m.windowLayer(w); layer != nil {
+		m.RemoveLayer(layer)
+	}
+	removeWindow(m.logicalRoot, w)
+	w.minimized = nil
+	w.docked = nil
+
+	return m
+}
+
+// Minimize shrinks the window to its title row. Only the windows of vertical
+// layouts can be minimized, a maximized window is restored first.
+func (m *Manager) Minimize(w *Window) *Manager {
+	l, item := findItem(m.logicalRoot, w)
+	if item == nil || l.direction != VerticalLayout || w.minimized != nil {
+		return m
+	}
+	if m.IsMaximazed(w) {
+		m.Restore()
+	}
+
+	w.minimized = &Item{Size: item.Size, Unit: item.Unit}
+	item.Size, item.Unit = 1, FixedUnit
+
+	return m
+}
+
+// Unminimize gives the minimized window the size it had before back
+func (m *Manager) Unminimize(w *Window) *Manager {
+	if w.minimized == nil {
+		return m
+	}
+
+	if _, item := findItem(m.logicalRoot, w); item != nil {
+		item.Size, item.Unit = w.minimized.Size, w.minimized.Unit
+	}
+	w.minimized = nil
+
+	return m
+}
+
+// IsMinimized returns true if the window is minimized
+func (m *Manager) IsMinimized(w *Window) bool {
+	return w.minimized != nil
+}
+
+// SetSplitFunc sets the function creating the window which the Split entry of
+// the context menu places next to the window it was opened on. The entry is
+// only offered once the function is set. It is called with the lock of the
+// manager held, see Manager.
+func (m *Manager) SetSplitFunc(split func(w *Window) *Window) *Manager {
+	m.split = split
+	return m
+}
+
+// Split replaces the window in its layout with a layout in the other direction
+// sharing the space of the window equally between the window and the new one
+func (m *Manager) Split(w, other *Window) *Manager {
+	l, item := findItem(m.logicalRoot, w)
+	if item == nil {
+		return m
+	}
+	if m.IsMaximazed(w) {
+		m.Restore()
+	}
+	m.Unminimize(w)
+
+	direction := HorizontalLayout
+	if l.direction == HorizontalLayout {
+		direction = VerticalLayout
+	}
+	item.Primitive = NewLayout().
+		SetDirection(direction).
+		SetSplitter(l.splitterFlag).
+		AddItemWeight(w, 1).
+		AddItemWeight(other, 1)
+
+	return m
+}
+
+// findItem returns the layout item holding the primitive and its layout, nil
+// if the primitive is not in the tree
+func findItem(p tview.Primitive, target tview.Primitive) (*Layout, *Item) {
+	l, ok := p.(*Layout)
+	if !ok {
+		return nil, nil
+	}
+
+	for _, item := range l.items {
+		if item.Primitive == target {
+			return l, item
+		}
+		if l, item := findItem(item.Primitive, target); item != nil {
+			return l, item
+		}
+	}
+
+	return nil, nil
+}
+
+// removeWindow removes the window from the layout tree, it returns true if the
+// window was found
+func removeWindow(p tview.Primitive, w *Window) bool {
+	l, ok := p.(*Layout)
+	if !ok {
+		return false
+	}
+
+	for i, item := range l.items {
+		if item.Primitive == w {
+			l.RemoveItem(i)
+			return true
+		}
+		if removeWindow(item.Primitive, w) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// openMenu opens the context menu of the window at the given screen position
+func (m *Manager) openMenu(w *Window, x, y int, setFocus func(p tview.Primitive)) {
+	maximize := &MenuItem{Label: "Maximize", action: func(w *Window) {
+		m.Maximize(w)
+		setFocus(w)
+	}}
+	if m.IsMaximazed(w) {
+		maximize = &MenuItem{Label: "Restore", action: func(w *Window) {
+			m.Restore()
+			setFocus(w)
+		}}
+	}
+	items := []*MenuItem{maximize, {Label: "Float", action: func(w *Window) {
+		m.Float(w)
+		setFocus(w)
+	}}}
+	if m.IsFloating(w) {
+		items = []*MenuItem{{Label: "Dock", action: func(w *Window) {
+			m.Dock(w)
+			setFocus(w)
+		}}}
+	}
+
+	if l, _ := findItem(m.logicalRoot, w); l != nil && l.direction == VerticalLayout {
+		minimize := &MenuItem{Label: "Minimize", action: func(w *Window) {
+			m.Minimize(w)
+		}}
+		if m.IsMinimized(w) {
+			minimize = &MenuItem{Label: "Unminimize", action: func(w *Window) {
+				m.Unminimize(w)
+				setFocus(w)
+			}}
+		}
+		items = append(items, minimize)
+	}
+
+	if m.split != nil && !m.IsFloating(w) {
+		items = append(items, &MenuItem{Label: "Split", action: func(w *Window) {
+			if other := m.split(w); other != nil {
+				m.Split(w, other)
+				setFocus(other)
+			}
+		}})
+	}
+
+	items = append(items, w.menuItems...)
+	items = append(items, &MenuItem{Label: "Close", action: func(w *Window) {
+		focused := w.HasFocus()
+		m.Close(w)
+		if windows := collectWindows(m.visibleRoot); focused && len(windows) > 0 {
+			setFocus(windows[0])
+		}
+	}})
+
+	m.menu = &contextMenu{
+		window: w,
+		items:  items,
+		x:      x,
+		y:      y,
+	}
+	m.menu.selectFirst()
+}
+
+// titleBarAt returns the visible window whose title bar is at the given screen
+// position, if any
+func (m *Manager) titleBarAt(x, y int) *Window {
+	// the layers hide the title bars below them
+	if layer := m.layerAt(x, y); layer != nil {
+		if w, ok := layer.Primitive.(*Window); ok && w.border && y == layer.Y {
+			return w
+		}
+		return nil
+	}
+
+	for _, w := range collectWindows(m.visibleRoot) {
+		wx, wy, width, _ := w.GetRect()
+		if w.border && y == wy && x >= wx && x < wx+width {
+			return w
+		}
+	}
+	return nil
+}
+
+// rect returns the screen area of the menu, moved to fit into the given area
+func (c *contextMenu) rect(x, y, width, height int) (int, int, int, int) {
+	menuWidth := 0
+	for _, item := range c.items {
+		if w := tview.TaggedStringWidth(tview.Escape(item.Label)); w > menuWidth {
+			menuWidth = w
+		}
+	}
+	menuWidth += 2
+	menuHeight := len(c.items)
+
+	menuX, menuY := c.x, c.y+1
+	if menuX+menuWidth > x+width {
+		menuX = x + width - menuWidth
+	}
+	if menuY+menuHeight > y+height {
+		menuY = c.y - menuHeight
+	}
+	if menuX < x {
+		menuX = x
+	}
+	if menuY < y {
+		menuY = y
+	}
+
+	return menuX, menuY, menuWidth, menuHeight
+}
+
+// draw draws the menu over the windows, inside the given area
+func (c *contextMenu) draw(screen tcell.Screen, theme *Theme, x, y, width, height int) {
+	menuX, menuY, menuWidth, menuHeight := c.rect(x, y, width, height)
+
+	for i := 0; i < menuHeight; i++ {
+		item := c.items[i]
+		style := theme.Overlay
+		if i == c.selected {
+			style = theme.OverlaySelected
+		}
+		if item.Disabled {
+			style = style.Dim(true)
+		}
+
+		for x_ := menuX; x_ < menuX+menuWidth; x_++ {
+			screen.SetContent(x_, menuY+i, ' ', nil, style)
+		}
+		styledPrint(screen, tview.Escape(item.Label), menuX+1, menuY+i, menuWidth-2, tview.AlignLeft, style)
+	}
+}
+
+// move selects the next item which is not disabled in the given direction
+func (c *contextMenu) move(step int) {
+	for i := 1; i <= len(c.items); i++ {
+		next := (c.selected + step*i + len(c.items)*i) % len(c.items)
+		if !c.items[next].Disabled {
+			c.selected = next
+			return
+		}
+	}
+}
+
+// selectFirst selects the first item which is not disabled
+func (c *contextMenu) selectFirst() {
+	c.selected = len(c.items) - 1
+	c.move(1)
+}
+
+// selectMenuItem closes the menu and invokes the built-in action of the
+// selected item, the caller holds the lock. It returns the callback of an item
+// added by the application, which is invoked without the lock, if any.
+func (m *Manager) selectMenuItem() func() {
+	menu := m.menu
+	m.menu = nil
+
+	if menu.selected < 0 || menu.selected >= len(menu.items) {
+		return nil
+	}
+	item := menu.items[menu.selected]
+	if item.Disabled {
+		return nil
+	}
+
+	if item.action != nil {
+		item.action(menu.window)
+		return nil
+	}
+	if item.Selected == nil {
+		return nil
+	}
+	return func() { item.Selected(menu.window) }
+}
+
+// handleMenuKey navigates the open menu, it returns the callback of the
+// application item to invoke, if any. All keys are consumed while the menu is
+// open.
+func (m *Manager) handleMenuKey(event *tcell.EventKey) func() {
+	switch event.Key() {
+	case tcell.KeyUp, tcell.KeyBacktab:
+		m.menu.move(-1)
+	case tcell.KeyDown, tcell.KeyTab:
+		m.menu.move(1)
+	case tcell.KeyHome:
+		m.menu.selectFirst()
+	case tcell.KeyEnd:
+		m.menu.selected = 0
+		m.menu.move(-1)
+	case tcell.KeyEnter:
+		return m.selectMenuItem()
+	case tcell.KeyEscape:
+		m.menu = nil
+	}
+
+	return nil
+}
+
+// handleMenuMouse highlights the item under the mouse and selects the clicked
+// one, a click outside the menu closes it. It returns the callback of the
+// application item to invoke, if any. All mouse events are consumed while the
+// menu is open.
+func (m *Manager) handleMenuMouse(action tview.MouseAction, event *tcell.EventMouse) func() {
+	// the menu is drawn inside the border of the manager
+	x, y, width, height := m.Box.GetInnerRect()
+	menuX, menuY, menuWidth, menuHeight := m.menu.rect(x, y, width, height)
+
+	mouseX, mouseY := event.Position()
+	inside := mouseX >= menuX && mouseX < menuX+menuWidth && mouseY >= menuY && mouseY < menuY+menuHeight
+
+	switch action {
+	case tview.MouseMove:
+		if inside {
+			m.menu.selected = mouseY - menuY
+		}
+	case tview.MouseLeftDown, tview.MouseRightDown, tview.MouseMiddleDown:
+		if !inside {
+			m.menu = nil
+		}
+	case tview.MouseLeftClick:
+		if inside {
+			m.menu.selected = mouseY - menuY
+			return m.selectMenuItem()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package tilman
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newMenuManager creates a 40x10 manager with two bordered windows stacked in
+// a vertical layout
+func newMenuManager() (*Manager, *Window, *Window) {
+	top := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle("top")
+	bottom := NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle("bottom")
+	root := NewLayout().SetDirection(VerticalLayout).
+		AddItemWeight(top, 1).
+		AddItemWeight(bottom, 1)
+
+	m := NewWindowManager().SetRoot(root)
+	m.SetRect(0, 0, 40, 10)
+	return m, top, bottom
+}
+
+// menuLabels returns the labels of the open menu, nil if it is closed
+func menuLabels(m *Manager) []string {
+	if m.menu == nil {
+		return nil
+	}
+
+	var labels []string
+	for _, item := range m.menu.items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+// openWindowMenu draws the manager and opens the menu of the window with a right
+// click on its title bar
+func openWindowMenu(t *testing.T, m *Manager, w *Window, focus *focuser) {
+	t.Helper()
+
+	m.Draw(newSimulationScreen(t, 40, 10))
+	x, y, _, _ := w.GetRect()
+	event := tcell.NewEventMouse(x+1, y, tcell.Button2, tcell.ModNone)
+	m.MouseHandler()(tview.MouseRightClick, event, focus.setFocus)
+	if m.menu == nil {
+		t.Fatalf("the menu of %s was not opened", w.GetTitle())
+	}
+}
+
+// menuKeys sends the keys to the manager
+func menuKeys(m *Manager, focus *focuser, keys ...tcell.Key) {
+	for _, key := range keys {
+		m.InputHandler()(tcell.NewEventKey(key, 0, tcell.ModNone), focus.setFocus)
+	}
+}
+
+func TestMenuMaximizeMinimize(t *testing.T) {
+	m, top, bottom := newMenuManager()
+	focus := &focuser{}
+
+	openWindowMenu(t, m, bottom, focus)
+	if labels := menuLabels(m); !reflect.DeepEqual(labels, []string{"Maximize", "Float", "Minimize", "Close"}) {
+		t.Errorf("the menu offers %q", labels)
+	}
+	menuKeys(m, focus, tcell.KeyEnter)
+	if !m.IsMaximazed(bottom) || !bottom.HasFocus() || m.menu != nil {
+		t.Error("the window was not maximized and focused")
+	}
+
+	openWindowMenu(t, m, bottom, focus)
+	if labels := menuLabels(m); labels[0] != "Restore" {
+		t.Errorf("the menu of the maximized window offers %q", labels)
+	}
+	menuKeys(m, focus, tcell.KeyEnter)
+	if m.IsMaximazed(bottom) {
+		t.Error("the window was not restored")
+	}
+
+	openWindowMenu(t, m, top, focus)
+	menuKeys(m, focus, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter)
+	m.Draw(newSimulationScreen(t, 40, 10))
+	if _, _, _, height := top.GetRect(); !m.IsMinimized(top) || height != 1 {
+		t.Errorf("the minimized window has a height of %d, want 1", height)
+	}
+	if _, _, _, height := bottom.GetRect(); height != 8 {
+		t.Errorf("the other window has a height of %d, want 8", height)
+	}
+
+	openWindowMenu(t, m, top, focus)
+	if labels := menuLabels(m); labels[2] != "Unminimize" {
+		t.Errorf("the menu of the minimized window offers %q", labels)
+	}
+	menuKeys(m, focus, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter)
+	m.Draw(newSimulationScreen(t, 40, 10))
+	if _, _, _, height := top.GetRect(); m.IsMinimized(top) || height != 4 {
+		t.Errorf("the unminimized window has a height of %d, want 4", height)
+	}
+
+	// the windows of horizontal layouts cannot be minimized
+	m.GetRoot().SetDirection(HorizontalLayout)
+	openWindowMenu(t, m, top, focus)
+	if labels := menuLabels(m); !reflect.DeepEqual(labels, []string{"Maximize", "Float", "Close"}) {
+		t.Errorf("the menu in a horizontal layout offers %q", labels)
+	}
+}
+
+func TestMenuSplitClose(t *testing.T) {
+	m, top, bottom := newMenuManager()
+	focus := &focuser{}
+
+	var split *Window
+	m.SetSplitFunc(func(w *Window) *Window {
+		split = NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTitle("split of " + w.GetTitle())
+		return split
+	})
+
+	openWindowMenu(t, m, bottom, focus)
+	if labels := menuLabels(m); !reflect.DeepEqual(labels, []string{"Maximize", "Float", "Minimize", "Split", "Close"}) {
+		t.Errorf("the menu offers %q", labels)
+	}
+	menuKeys(m, focus, tcell.KeyEnd, tcell.KeyUp, tcell.KeyEnter)
+
+	l, ok := m.GetRoot().GetItem(1).Primitive.(*Layout)
+	if !ok || l.GetDirection() != HorizontalLayout || l.CountItems() != 2 ||
+		l.GetItem(0).Primitive != bottom || l.GetItem(1).Primitive != split {
+		t.Fatal("the window was not split into a horizontal layout")
+	}
+	if !split.HasFocus() {
+		t.Error("the new window was not focused")
+	}
+
+	// the focused window is closed, the first one is focused
+	openWindowMenu(t, m, split, focus)
+	menuKeys(m, focus, tcell.KeyEnd, tcell.KeyEnter)
+	if l.CountItems() != 1 || !top.HasFocus() {
+		t.Error("the window was not closed")
+	}
+}
+
+func TestMenuApplicationItems(t *testing.T) {
+	m, _, bottom := newMenuManager()
+	focus := &focuser{}
+
+	var selected []string
+	bottom.AddMenuItem("Disabled", func(w *Window) { selected = append(selected, "disabled") })
+	bottom.AddMenuItem("Rename", func(w *Window) {
+		// the application items are invoked without the lock
+		m.Lock()
+		defer m.Unlock()
+		selected = append(selected, w.GetTitle())
+	})
+	bottom.GetMenuItems()[0].Disabled = true
+
+	openWindowMenu(t, m, bottom, focus)
+	// past Float, Minimize and the disabled item
+	within(t, "application item", func() { menuKeys(m, focus, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyEnter) })
+	if !reflect.DeepEqual(selected, []string{"bottom"}) {
+		t.Errorf("the selected items are %q", selected)
+	}
+
+	// clicks select the items, except the disabled ones
+	openWindowMenu(t, m, bottom, focus)
+	x, y, _, _ := m.menu.rect(m.GetInnerRect())
+	click := func(x, y int) {
+		event := tcell.NewEventMouse(x, y, tcell.Button1, tcell.ModNone)
+		m.MouseHandler()(tview.MouseLeftClick, event, focus.setFocus)
+	}
+	click(x+1, y+3)
+	if m.menu != nil || len(selected) != 1 {
+		t.Errorf("a click on the disabled item selected %q", selected)
+	}
+
+	openWindowMenu(t, m, bottom, focus)
+	within(t, "application item", func() { click(x+1, y+4) })
+	if len(selected) != 2 {
+		t.Errorf("a click on the item did not select it, %q", selected)
+	}
+
+	// a click outside closes the menu
+	openWindowMenu(t, m, bottom, focus)
+	event := tcell.NewEventMouse(0, 9, tcell.Button1, tcell.ModNone)
+	m.MouseHandler()(tview.MouseLeftDown, event, focus.setFocus)
+	if m.menu != nil {
+		t.Error("the menu was not closed by a click outside")
+	}
+}
+
+func TestMenuSelectFirst(t *testing.T) {
+	menu := &contextMenu{items: []*MenuItem{{Disabled: true}, {}, {}}}
+	menu.selectFirst()
+	if menu.selected != 1 {
+		t.Errorf("the item %d is selected, want the first one not disabled", menu.selected)
+	}
+}
@@ -0,0 +1,162 @@
+package tilman
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// EventKind is the kind of a recorded event
+type EventKind string
+
+const (
+	EventKey    EventKind = "key"    // a key event sent to the manager
+	EventMouse  EventKind = "mouse"  // a mouse event sent to the manager
+	EventResize EventKind = "resize" // the manager was drawn with a new size
+)
+
+// RecordedEvent is an event which reached the manager, written as a line of
+// JSON by the recorder
+type RecordedEvent struct {
+	// time since the recording started
+	Time time.Duration `json:"time"`
+	Kind EventKind     `json:"kind"`
+
+	// key
+	Key  tcell.Key `json:"key,omitempty"`
+	Rune rune      `json:"rune,omitempty"`
+
+	// key and mouse
+	Modifiers tcell.ModMask `json:"modifiers,omitempty"`
+
+	// mouse, the position is relative to the top-left corner of the manager
+	Action  tview.MouseAction `json:"action,omitempty"`
+	X       int               `json:"x,omitempty"`
+	Y       int               `json:"y,omitempty"`
+	Buttons tcell.ButtonMask  `json:"buttons,omitempty"`
+
+	// resize
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+// Recorder writes the key, mouse and resize events reaching a manager with
+// their timing, so that they can be replayed to reproduce a layout (see
+// Manager.SetRecorder and ReadEvents). The mouse positions are relative to the
+// manager, so that they replay wherever the manager is drawn.
+//
+// When a primitive of the manager captures the mouse, the manager captures it
+// in its place and forwards the events, so that they are recorded as well.
+// Events sent to the application outside of the manager are not recorded.
+type Recorder struct {
+	encoder *json.Encoder
+	start   time.Time
+	err     error
+
+	// size of the manager at the last draw
+	width, height int
+}
+
+// NewRecorder creates a recorder writing one event per line to the writer,
+// the time of the events is relative to its creation
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		encoder: json.NewEncoder(w),
+		start:   time.Now(),
+	}
+}
+
+// Err returns the first error writing an event, the recorder stops writing
+// after it
+func (r *Recorder) Err() error {
+	return r.err
+}
+
+// record writes the event with the time elapsed since the start
+func (r *Recorder) record(event RecordedEvent) {
+	if r.err != nil {
+		return
+	}
+
+	event.Time = time.Since(r.start)
+	r.err = r.encoder.Encode(event)
+}
+
+// recordKey records a key event
+func (r *Recorder) recordKey(event *tcell.EventKey) {
+	r.record(RecordedEvent{
+		Kind:      EventKey,
+		Key:       event.Key(),
+		Rune:      event.Rune(),
+		Modifiers: event.Modifiers(),
+	})
+}
+
+// recordMouse records a mouse event relative to the origin of the manager
+func (r *Recorder) recordMouse(action tview.MouseAction, event *tcell.EventMouse, originX, originY int) {
+	x, y := event.Position()
+	r.record(RecordedEvent{
+		Kind:      EventMouse,
+		Action:    action,
+		X:         x - originX,
+		Y:         y - originY,
+		Buttons:   event.Buttons(),
+		Modifiers: event.Modifiers(),
+	})
+}
+
+// recordSize records a resize event if the size changed since the last draw
+func (r *Recorder) recordSize(width, height int) {
+	if width == r.width && height == r.height {
+		return
+	}
+
+	r.width, r.height = width, height
+	r.record(RecordedEvent{
+		Kind:   EventResize,
+		Width:  width,
+		Height: height,
+	})
+}
+
+// ReadEvents reads the events written by a recorder
+func ReadEvents(r io.Reader) ([]RecordedEvent, error) {
+	var events []RecordedEvent
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch event.Kind {
+		case EventKey, EventMouse, EventResize:
+		default:
+			return nil, fmt.Errorf("line %d: unknown event kind %q", line, event.Kind)
+		}
+		events = append(events, event)
+	}
+
+	return events, scanner.Err()
+}
+
+// SetRecorder records the events reaching the manager from now on, a nil
+// recorder stops recording
+func (m *Manager) SetRecorder(recorder *Recorder) *Manager {
+	m.recorder = recorder
+	return m
+}
+
+// GetRecorder returns the recorder of the manager, if any
+func (m *Manager) GetRecorder() *Recorder {
+	return m.recorder
+}
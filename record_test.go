@@ -0,0 +1,54 @@
+package tilman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestRecorder(t *testing.T) {
+	var recording bytes.Buffer
+	m := NewWindowManager().
+		SetRoot(NewLayout().AddItemWeight(NewWindow().SetRoot(tview.NewBox()), 1)).
+		SetRecorder(NewRecorder(&recording))
+	focus := &focuser{}
+	screen := newSimulationScreen(t, 40, 10)
+
+	// the manager is drawn away from the origin of the screen
+	m.SetRect(5, 3, 20, 6)
+	m.Draw(screen)
+	m.Draw(screen)
+	m.MouseHandler()(tview.MouseLeftClick, tcell.NewEventMouse(7, 4, tcell.Button1, tcell.ModCtrl), focus.setFocus)
+	m.InputHandler()(tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModAlt), focus.setFocus)
+	m.SetRect(5, 3, 30, 7)
+	m.Draw(screen)
+
+	if err := m.GetRecorder().Err(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := ReadEvents(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RecordedEvent{
+		{Kind: EventResize, Width: 20, Height: 6},
+		{Kind: EventMouse, Action: tview.MouseLeftClick, X: 2, Y: 1, Buttons: tcell.Button1, Modifiers: tcell.ModCtrl},
+		{Kind: EventKey, Key: tcell.KeyRune, Rune: 'q', Modifiers: tcell.ModAlt},
+		{Kind: EventResize, Width: 30, Height: 7},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events were recorded, want %d: %+v", len(events), len(want), events)
+	}
+	for i := range want {
+		if events[i].Time < 0 || i > 0 && events[i].Time < events[i-1].Time {
+			t.Errorf("the event %d was recorded at %v", i, events[i].Time)
+		}
+		events[i].Time = 0
+		if events[i] != want[i] {
+			t.Errorf("the event %d is %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
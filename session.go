@@ -0,0 +1,436 @@
+package tilman
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+const sessionVersion = 1
+
+// WindowFactory recreates a window of a registered kind from the user data
+// saved with the session
+type WindowFactory func(data json.RawMessage) (*Window, error)
+
+// Session saves the state of a window manager to a file and restores it: the
+// layout tree with its sizes and splitters, the floating windows with their
+// geometry, the focused and maximized windows and the user data of every
+// window.
+//
+// Only windows with a kind (see Window.SetKind) are saved. On restore every
+// window is recreated by the factory registered for its kind, windows whose
+// factory is missing or fails are left out.
+//
+// The manager has no workspaces. The layers showing other primitives than
+// windows with a kind are not saved, and are kept on restore.
+type Session struct {
+	sync.Mutex
+
+	manager   *Manager
+	path      string
+	factories map[string]WindowFactory
+
+	// last written state and the running autosave, if any
+	saved     []byte
+	autosaver *autosaver
+}
+
+// autosaver saves the session once the manager did not change for a delay
+type autosaver struct {
+	timer  *time.Timer
+	remove func() // removes the observer of the manager
+}
+
+type sessionState struct {
+	Version int            `json:"version"`
+	Root    sessionNode    `json:"root"`
+	Layers  []sessionLayer `json:"layers,omitempty"`
+}
+
+type sessionNode struct {
+	Size int      `json:"size,omitempty"`
+	Unit SizeUnit `json:"unit,omitempty"`
+
+	// layout
+	Direction Direction     `json:"direction,omitempty"`
+	Splitter  bool          `json:"splitter,omitempty"`
+	Items     []sessionNode `json:"items,omitempty"`
+
+	// window
+	Window *sessionWindow `json:"window,omitempty"`
+}
+
+type sessionLayer struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	Mode    CompositeMode `json:"mode,omitempty"`
+	Opacity float64       `json:"opacity"`
+	Shadow  bool          `json:"shadow,omitempty"`
+
+	Window *sessionWindow `json:"window"`
+}
+
+type sessionWindow struct {
+	Kind      string          `json:"kind"`
+	Title     string          `json:"title,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Focused   bool            `json:"focused,omitempty"`
+	Maximized bool            `json:"maximized,omitempty"`
+}
+
+// DefaultSessionPath returns the path of the session file for the given
+// application in the user's configuration directory
+func DefaultSessionPath(app string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, app, "session.json"), nil
+}
+
+// NewSession creates a session saving the state of the manager to the given file
+func NewSession(manager *Manager, path string) *Session {
+	return &Session{
+		manager:   manager,
+		path:      path,
+		factories: make(map[string]WindowFactory),
+	}
+}
+
+// GetPath returns the path of the session file
+func (s *Session) GetPath() string {
+	return s.path
+}
+
+// RegisterFactory registers the factory which recreates windows of the given kind
+func (s *Session) RegisterFactory(kind string, factory WindowFactory) *Session {
+	s.Lock()
+	defer s.Unlock()
+
+	s.factories[kind] = factory
+	return s
+}
+
+// Save writes the current state of the manager to the session file. It locks
+// the manager, see Manager.
+func (s *Session) Save() error {
+	data, err := s.lockedEncode()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	return s.write(data)
+}
+
+// Restore replaces the layout of the manager with the one saved in the session
+// file. If the file does not exist, an error satisfying os.IsNotExist is
+// returned and the manager is left untouched. It locks the manager, see
+// Manager.
+func (s *Session) Restore() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+
+	var state sessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("invalid session file %s: %w", s.path, err)
+	}
+	if state.Version != sessionVersion {
+		return fmt.Errorf("unsupported session version %d in %s", state.Version, s.path)
+	}
+	if state.Root.Window != nil {
+		return fmt.Errorf("invalid session file %s: root is not a layout", s.path)
+	}
+
+	var focused, maximized *Window
+	root := s.buildLayout(&state.Root, &focused, &maximized)
+	layers := s.buildLayers(state.Layers, &focused)
+
+	s.manager.Lock()
+	s.restore(root, layers, focused, maximized)
+	s.manager.Unlock()
+
+	s.Lock()
+	s.saved = data
+	s.Unlock()
+
+	return nil
+}
+
+// restore replaces the layout and the floating windows of the manager, the
+// caller holds its lock
+func (s *Session) restore(root *Layout, layers []*Layer, focused, maximized *Window) {
+	s.manager.SetRoot(root)
+	for _, layer := range s.manager.GetLayers() {
+		if w, ok := layer.Primitive.(*Window); ok && w.kind != "" {
+			s.manager.RemoveLayer(layer)
+		}
+	}
+	for _, layer := range layers {
+		s.manager.AddLayer(layer)
+	}
+	if maximized != nil {
+		s.manager.Maximize(maximized)
+	}
+	if focused != nil {
+		s.manager.pendingFocus = focused
+	}
+}
+
+// StartAutosave saves the state of the manager once it did not change for the
+// given delay, i.e. the delay after the last event handled by the manager or
+// the last Manager.Update. The state is only written if it differs from the
+// one saved last. Errors are reported to onError, which may be nil, in another
+// goroutine.
+func (s *Session) StartAutosave(delay time.Duration, onError func(err error)) {
+	s.StopAutosave()
+
+	a := &autosaver{}
+	a.timer = time.AfterFunc(delay, func() {
+		if err := s.autosave(a); err != nil && onError != nil {
+			onError(err)
+		}
+	})
+	a.timer.Stop()
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.autosaver = a
+	a.remove = s.manager.observe(func() {
+		a.timer.Reset(delay)
+	})
+}
+
+// StopAutosave stops the autosave started by StartAutosave
+func (s *Session) StopAutosave() {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.autosaver != nil {
+		s.autosaver.remove()
+		s.autosaver.timer.Stop()
+		s.autosaver = nil
+	}
+}
+
+func (s *Session) autosave(a *autosaver) error {
+	data, err := s.lockedEncode()
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.autosaver != a || bytes.Equal(data, s.saved) {
+		return nil
+	}
+
+	return s.write(data)
+}
+
+// write atomically replaces the session file, the session must be locked
+func (s *Session) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+
+	s.saved = data
+	return nil
+}
+
+// lockedEncode encodes the state of the manager with the manager locked
+func (s *Session) lockedEncode() ([]byte, error) {
+	s.manager.Lock()
+	defer s.manager.Unlock()
+
+	return s.encode()
+}
+
+// encode encodes the state of the manager, the caller holds its lock
+func (s *Session) encode() ([]byte, error) {
+	root, err := s.encodeLayout(s.manager.GetRoot())
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []sessionLayer
+	for _, layer := range s.manager.GetLayers() {
+		w, ok := layer.Primitive.(*Window)
+		if !ok || w.kind == "" {
+			continue
+		}
+
+		window, err := s.encodeWindow(w)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, sessionLayer{
+			X:       layer.X,
+			Y:       layer.Y,
+			Width:   layer.Width,
+			Height:  layer.Height,
+			Mode:    layer.Mode,
+			Opacity: layer.Opacity,
+			Shadow:  layer.Shadow,
+			Window:  window,
+		})
+	}
+
+	return json.MarshalIndent(&sessionState{
+		Version: sessionVersion,
+		Root:    root,
+		Layers:  layers,
+	}, "", "  ")
+}
+
+func (s *Session) encodeLayout(l *Layout) (sessionNode, error) {
+	node := sessionNode{
+		Direction: l.direction,
+		Splitter:  l.splitterFlag,
+	}
+
+	for _, item := range l.items {
+		var child sessionNode
+
+		switch p := item.Primitive.(type) {
+		case *Layout:
+			var err error
+			if child, err = s.encodeLayout(p); err != nil {
+				return node, err
+			}
+		case *Window:
+			if p.kind == "" {
+				continue
+			}
+
+			var err error
+			if child.Window, err = s.encodeWindow(p); err != nil {
+				return node, err
+			}
+		default:
+			continue
+		}
+
+		child.Size, child.Unit = item.Size, item.Unit
+		node.Items = append(node.Items, child)
+	}
+
+	return node, nil
+}
+
+func (s *Session) encodeWindow(w *Window) (*sessionWindow, error) {
+	window := &sessionWindow{
+		Kind:      w.kind,
+		Title:     w.GetTitle(),
+		Focused:   w.HasFocus(),
+		Maximized: s.manager.IsMaximazed(w),
+	}
+	if w.userData != nil {
+		data, err := json.Marshal(w.userData)
+		if err != nil {
+			return nil, fmt.Errorf("user data of window %q: %w", w.GetTitle(), err)
+		}
+		window.Data = data
+	}
+
+	return window, nil
+}
+
+func (s *Session) buildLayout(node *sessionNode, focused, maximized **Window) *Layout {
+	layout := NewLayout().
+		SetDirection(node.Direction).
+		SetSplitter(node.Splitter)
+
+	for i := range node.Items {
+		child := &node.Items[i]
+
+		var p tview.Primitive
+		if child.Window == nil {
+			p = s.buildLayout(child, focused, maximized)
+		} else {
+			w := s.buildWindow(child.Window)
+			if w == nil {
+				continue
+			}
+			if child.Window.Focused {
+				*focused = w
+			}
+			if child.Window.Maximized {
+				*maximized = w
+			}
+			p = w
+		}
+
+		layout.addItem(p, child.Size, child.Unit)
+	}
+
+	return layout
+}
+
+func (s *Session) buildLayers(saved []sessionLayer, focused **Window) []*Layer {
+	var layers []*Layer
+	for _, l := range saved {
+		if l.Window == nil {
+			continue
+		}
+		w := s.buildWindow(l.Window)
+		if w == nil {
+			continue
+		}
+		if l.Window.Focused {
+			*focused = w
+		}
+
+		layer := NewLayer(w, l.X, l.Y, l.Width, l.Height)
+		layer.Mode, layer.Opacity, layer.Shadow = l.Mode, l.Opacity, l.Shadow
+		layers = append(layers, layer)
+	}
+
+	return layers
+}
+
+func (s *Session) buildWindow(saved *sessionWindow) *Window {
+	s.Lock()
+	factory, ok := s.factories[saved.Kind]
+	s.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	w, err := factory(saved.Data)
+	if err != nil || w == nil {
+		return nil
+	}
+
+	w.SetKind(saved.Kind)
+	if saved.Title != "" {
+		w.SetTitle(saved.Title)
+	}
+
+	return w
+}
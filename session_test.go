@@ -0,0 +1,225 @@
+package tilman
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newTextWindow creates a window of the kind "text" showing its user data
+func newTextWindow(text string) *Window {
+	return NewWindow().SetRoot(tview.NewTextView().SetText(text)).SetKind("text").SetUserData(text)
+}
+
+// textFactory recreates the windows created by newTextWindow
+func textFactory(data json.RawMessage) (*Window, error) {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return nil, err
+	}
+	return newTextWindow(text), nil
+}
+
+// newSessionManager creates a manager with a window on the left and three
+// windows on the right, the first of them without a kind
+func newSessionManager() (*Manager, []*Window) {
+	windows := []*Window{
+		newTextWindow("left").SetTitle("Left"),
+		NewWindow().SetRoot(tview.NewBox()),
+		newTextWindow("right").SetTitle("Right"),
+		newTextWindow("log").SetKind("log"),
+	}
+
+	root := NewLayout().SetDirection(HorizontalLayout).SetSplitter(true).
+		AddItem(windows[0], 20).
+		AddItemWeight(NewLayout().SetDirection(VerticalLayout).
+			AddItemPercent(windows[1], 10).
+			AddItemPercent(windows[2], 30).
+			AddItemWeight(windows[3], 2), 1)
+
+	return NewWindowManager().SetRoot(root), windows
+}
+
+func TestSessionSaveRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app", "session.json")
+
+	manager, windows := newSessionManager()
+	windows[2].Focus(func(p tview.Primitive) { p.Focus(nil) })
+	manager.Maximize(windows[0])
+	if err := NewSession(manager, path).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewWindowManager()
+	session := NewSession(restored, path).
+		RegisterFactory("text", textFactory).
+		RegisterFactory("log", textFactory)
+	if err := session.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := restored.GetRoot()
+	if root.GetDirection() != HorizontalLayout || !root.splitterFlag || root.CountItems() != 2 {
+		t.Fatalf("the root has direction %d and %d items", root.GetDirection(), root.CountItems())
+	}
+	left, ok := root.GetItem(0).Primitive.(*Window)
+	if !ok || left.GetUserData() != "left" || left.GetTitle() != "Left" || root.GetItem(0).Size != 20 {
+		t.Errorf("the first item is %T, want the left window with size 20", root.GetItem(0).Primitive)
+	}
+	if !restored.IsMaximazed(left) {
+		t.Error("the left window is not maximized")
+	}
+
+	// the window without a kind is left out
+	nested, ok := root.GetItem(1).Primitive.(*Layout)
+	if !ok || nested.GetDirection() != VerticalLayout || nested.CountItems() != 2 {
+		t.Fatalf("the second item is %T, want a vertical layout with 2 items", root.GetItem(1).Primitive)
+	}
+	right, log := nested.GetItem(0), nested.GetItem(1)
+	if w, ok := right.Primitive.(*Window); !ok || w.GetUserData() != "right" || right.Size != 30 || right.Unit != PercentUnit {
+		t.Errorf("the right window is %T with size %d and unit %d", right.Primitive, right.Size, right.Unit)
+	} else if restored.pendingFocus != w {
+		t.Error("the right window does not receive the focus")
+	}
+	if w, ok := log.Primitive.(*Window); !ok || w.GetKind() != "log" || log.Size != 2 || log.Unit != WeightUnit {
+		t.Errorf("the log window is %T with size %d and unit %d", log.Primitive, log.Size, log.Unit)
+	}
+}
+
+func TestSessionMissingFactory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	manager, _ := newSessionManager()
+	if err := NewSession(manager, path).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewWindowManager()
+	session := NewSession(restored, path).
+		RegisterFactory("log", func(data json.RawMessage) (*Window, error) {
+			return nil, errors.New("no log")
+		})
+	if err := session.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := restored.GetRoot()
+	if root.CountItems() != 1 {
+		t.Fatalf("the root has %d items, want the empty layout", root.CountItems())
+	}
+	if nested := root.GetItem(0).Primitive.(*Layout); nested.CountItems() != 0 {
+		t.Errorf("the nested layout has %d items, want none", nested.CountItems())
+	}
+
+	if err := NewSession(restored, filepath.Join(t.TempDir(), "missing.json")).Restore(); !os.IsNotExist(err) {
+		t.Errorf("restoring a missing file returned %v", err)
+	}
+	if restored.GetRoot() != root {
+		t.Error("restoring a missing file changed the layout")
+	}
+}
+
+func TestSessionLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	manager, windows := newSessionManager()
+	floating := newTextWindow("float")
+	layer := NewLayer(floating, 5, 2, 20, 6)
+	layer.Mode, layer.Opacity, layer.Shadow = CompositeBlend, 0.5, true
+	manager.AddLayer(layer).AddLayer(NewLayer(tview.NewBox(), 0, 0, 3, 3))
+	floating.Focus(func(p tview.Primitive) { p.Focus(nil) })
+	if err := NewSession(manager, path).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the layers of the windows with a kind are replaced, the others are kept
+	restored := NewWindowManager()
+	kept := NewLayer(tview.NewBox(), 1, 1, 2, 2)
+	restored.AddLayer(NewLayer(newTextWindow("old"), 0, 0, 5, 5)).AddLayer(kept)
+	session := NewSession(restored, path).
+		RegisterFactory("text", textFactory).
+		RegisterFactory("log", textFactory)
+	if err := session.Restore(); err != nil {
+		t.Fatal(err)
+	}
+
+	layers := restored.GetLayers()
+	if len(layers) != 2 || layers[0] != kept {
+		t.Fatalf("the manager has %d layers, want the kept one and the floating window", len(layers))
+	}
+	l := layers[1]
+	w, ok := l.Primitive.(*Window)
+	if !ok || w.GetUserData() != "float" {
+		t.Fatalf("the layer shows %T, want the floating window", l.Primitive)
+	}
+	if l.X != 5 || l.Y != 2 || l.Width != 20 || l.Height != 6 {
+		t.Errorf("the floating window is at %d,%d %dx%d, want 5,2 20x6", l.X, l.Y, l.Width, l.Height)
+	}
+	if l.Mode != CompositeBlend || l.Opacity != 0.5 || !l.Shadow {
+		t.Errorf("the layer has the mode %d, the opacity %v and the shadow %v", l.Mode, l.Opacity, l.Shadow)
+	}
+	if restored.pendingFocus != w {
+		t.Error("the floating window does not receive the focus")
+	}
+	if restored.GetRoot().CountItems() != 2 || windows[0].HasFocus() {
+		t.Error("the layout was not restored with the floating window")
+	}
+}
+
+// waitForFile fails the test if the file is not written within seconds
+func waitForFile(t *testing.T, path, what string) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			return
+		}
+	}
+	t.Fatalf("%s was not written", what)
+}
+
+func TestSessionAutosave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+
+	manager, windows := newSessionManager()
+	session := NewSession(manager, path)
+	session.StartAutosave(10*time.Millisecond, func(err error) { t.Error(err) })
+	defer session.StopAutosave()
+
+	key := func() {
+		manager.InputHandler()(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), func(p tview.Primitive) {})
+	}
+
+	// saved once the events stop
+	key()
+	waitForFile(t, path, "the session after an event")
+
+	// an unchanged state is not written again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	key()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the unchanged session was written again: %v", err)
+	}
+
+	// a changed state is written
+	manager.Update(func() { windows[0].SetTitle("Changed") })
+	waitForFile(t, path, "the changed session")
+
+	// nothing is written once the autosave stopped
+	session.StopAutosave()
+	os.Remove(path)
+	manager.Update(func() { windows[0].SetTitle("Stopped") })
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the session was written after the autosave stopped: %v", err)
+	}
+}
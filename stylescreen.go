@@ -0,0 +1,132 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// StyleScreen implements tcell.Screen and transforms the style of all content
+// set through it, e.g. to dim a primitive without changing its colors
+type StyleScreen struct {
+	tcell.Screen
+	transform func(style tcell.Style) tcell.Style
+}
+
+// NewStyleScreen creates a screen which applies the transform to the style of
+// the content before setting it on the given screen
+func NewStyleScreen(screen tcell.Screen, transform func(style tcell.Style) tcell.Style) *StyleScreen {
+	return &StyleScreen{
+		Screen:    screen,
+		transform: transform,
+	}
+}
+
+// Fill implements tcell.Screen.Fill
+func (s *StyleScreen) Fill(ch rune, style tcell.Style) {
+	s.Screen.Fill(ch, s.transform(style))
+}
+
+// SetCell is an older API, and will be removed.  Please use
+// SetContent instead; SetCell is implemented in terms of SetContent.
+func (s *StyleScreen) SetCell(x int, y int, style tcell.Style, ch ...rune) {
+	if len(ch) > 0 {
+		s.SetContent(x, y, ch[0], ch[1:], style)
+	} else {
+		s.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// SetContent sets the contents of the given cell location with the transformed
+// style
+func (s *StyleScreen) SetContent(x int, y int, mainc rune, combc []rune, style tcell.Style) {
+	s.Screen.SetContent(x, y, mainc, combc, s.transform(style))
+}
+
+// Bounds returns the bounds of the wrapped screen, so that the clip regions
+// drawn through the style screen stay within the clip region it wraps
+func (s *StyleScreen) Bounds() (int, int, int, int) {
+	if parent, ok := s.Screen.(bounded); ok {
+		return parent.Bounds()
+	}
+
+	width, height := s.Screen.Size()
+	return 0, 0, width, height
+}
+
+// DimMode defines how the manager draws the windows which do not have focus
+type DimMode int
+
+const (
+	// DimNone draws unfocused windows like the focused one
+	DimNone DimMode = iota
+	// DimAttribute draws unfocused windows with the dim attribute
+	DimAttribute
+	// DimBlend blends the text colors of unfocused windows halfway toward their
+	// background, colors without RGB value are dimmed with the attribute
+	DimBlend
+)
+
+// dimTransform returns the style transform of the mode, nil for DimNone. The
+// background is used to blend cells without background color.
+func dimTransform(mode DimMode, background tcell.Color) func(tcell.Style) tcell.Style {
+	switch mode {
+	case DimAttribute:
+		return func(style tcell.Style) tcell.Style {
+			return style.Dim(true)
+		}
+	case DimBlend:
+		return func(style tcell.Style) tcell.Style {
+			fg, bg, _ := style.Decompose()
+			if bg == tcell.ColorDefault {
+				bg = background
+			}
+			if blended, ok := blendColors(fg, bg, 0.5); ok {
+				return style.Foreground(blended)
+			}
+			return style.Dim(true)
+		}
+	}
+
+	return nil
+}
+
+// blendColors returns the color the given amount of the way from the first
+// color to the second, 0.5 is halfway. It returns false if a color has no RGB
+// value such as tcell.ColorDefault.
+func blendColors(a, b tcell.Color, amount float64) (tcell.Color, bool) {
+	r1, g1, b1 := a.RGB()
+	r2, g2, b2 := b.RGB()
+	if r1 < 0 || r2 < 0 {
+		return a, false
+	}
+
+	mix := func(c1, c2 int32) int32 {
+		return c1 + int32(float64(c2-c1)*amount)
+	}
+	return tcell.NewRGBColor(mix(r1, r2), mix(g1, g2), mix(b1, b2)), true
+}
+
+// inheritDim passes the style transform for unfocused windows down the
+// primitive tree to every window
+func inheritDim(p tview.Primitive, transform func(tcell.Style) tcell.Style) {
+	switch p := p.(type) {
+	case *Layout:
+		for _, item := range p.items {
+			inheritDim(item.Primitive, transform)
+		}
+	case *Window:
+		p.dimTransform = transform
+	}
+}
+
+// SetDimUnfocused sets how the windows which do not have focus are drawn, so
+// that the focused window stands out in dense layouts
+func (m *Manager) SetDimUnfocused(mode DimMode) *Manager {
+	m.dimMode = mode
+	return m
+}
+
+// GetDimUnfocused returns how the windows which do not have focus are drawn
+func (m *Manager) GetDimUnfocused() DimMode {
+	return m.dimMode
+}
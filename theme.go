@@ -0,0 +1,205 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Theme defines the styles of windows, splitters and everything else drawn by
+// tilman. A theme set on the Manager applies to all layouts and windows it
+// contains, a theme set on a window overrides it for this window.
+type Theme struct {
+	// Background color of windows and layouts
+	Background tcell.Color
+
+	// Window borders
+	Border        tcell.Style
+	BorderFocused tcell.Style
+	BorderUrgent  tcell.Style
+
+	// Window titles, the foreground may be changed by color tags in the title
+	Title        tcell.Style
+	TitleFocused tcell.Style
+	TitleUrgent  tcell.Style
+
+	// Title bar buttons
+	Button         tcell.Style
+	ButtonHover    tcell.Style
+	ButtonPressed  tcell.Style
+	ButtonDisabled tcell.Style
+
+	// Layout splitters
+	Splitter         tcell.Style
+	SplitterFocused  tcell.Style
+	SplitterDragging tcell.Style
+
+	// Popups drawn over the windows such as menus and tooltips
+	Overlay         tcell.Style
+	OverlaySelected tcell.Style
+}
+
+// DefaultTheme returns a theme based on the current tview.Styles, it looks
+// like windows and layouts without any theme
+func DefaultTheme() *Theme {
+	border := tcell.StyleDefault.Foreground(tview.Styles.BorderColor)
+	title := tcell.StyleDefault.Foreground(tview.Styles.TitleColor)
+	button := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	overlay := tcell.StyleDefault.
+		Foreground(tview.Styles.PrimaryTextColor).
+		Background(tview.Styles.ContrastBackgroundColor)
+
+	return &Theme{
+		Background:       tview.Styles.PrimitiveBackgroundColor,
+		Border:           border,
+		BorderFocused:    border,
+		BorderUrgent:     tcell.StyleDefault.Foreground(tcell.ColorRed),
+		Title:            title,
+		TitleFocused:     title,
+		TitleUrgent:      tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true),
+		Button:           button,
+		ButtonHover:      button.Bold(true),
+		ButtonPressed:    button.Reverse(true),
+		ButtonDisabled:   tcell.StyleDefault.Foreground(tcell.ColorGray),
+		Splitter:         border,
+		SplitterFocused:  border,
+		SplitterDragging: border.Bold(true),
+		Overlay:          overlay,
+		OverlaySelected: tcell.StyleDefault.
+			Foreground(tview.Styles.ContrastBackgroundColor).
+			Background(tview.Styles.PrimaryTextColor),
+	}
+}
+
+// MonochromeTheme returns a theme which uses the terminal's default colors
+// only and highlights with text attributes
+func MonochromeTheme() *Theme {
+	return &Theme{
+		Background:       tcell.ColorDefault,
+		Border:           tcell.StyleDefault.Dim(true),
+		BorderFocused:    tcell.StyleDefault.Bold(true),
+		BorderUrgent:     tcell.StyleDefault.Reverse(true),
+		Title:            tcell.StyleDefault.Dim(true),
+		TitleFocused:     tcell.StyleDefault.Bold(true),
+		TitleUrgent:      tcell.StyleDefault.Reverse(true).Bold(true),
+		Button:           tcell.StyleDefault,
+		ButtonHover:      tcell.StyleDefault.Bold(true),
+		ButtonPressed:    tcell.StyleDefault.Reverse(true),
+		ButtonDisabled:   tcell.StyleDefault.Dim(true),
+		Splitter:         tcell.StyleDefault.Dim(true),
+		SplitterFocused:  tcell.StyleDefault,
+		SplitterDragging: tcell.StyleDefault.Bold(true),
+		Overlay:          tcell.StyleDefault.Reverse(true),
+		OverlaySelected:  tcell.StyleDefault.Bold(true),
+	}
+}
+
+// SolarizedDarkTheme returns a theme based on the Solarized dark palette
+func SolarizedDarkTheme() *Theme {
+	return &Theme{
+		Background:       tcell.NewHexColor(0x002b36),
+		Border:           tcell.StyleDefault.Foreground(tcell.NewHexColor(0x586e75)).Background(tcell.NewHexColor(0x002b36)),
+		BorderFocused:    tcell.StyleDefault.Foreground(tcell.NewHexColor(0x268bd2)).Background(tcell.NewHexColor(0x002b36)),
+		BorderUrgent:     tcell.StyleDefault.Foreground(tcell.NewHexColor(0xdc322f)).Background(tcell.NewHexColor(0x002b36)),
+		Title:            tcell.StyleDefault.Foreground(tcell.NewHexColor(0x839496)).Background(tcell.NewHexColor(0x002b36)),
+		TitleFocused:     tcell.StyleDefault.Foreground(tcell.NewHexColor(0xeee8d5)).Background(tcell.NewHexColor(0x002b36)).Bold(true),
+		TitleUrgent:      tcell.StyleDefault.Foreground(tcell.NewHexColor(0xdc322f)).Background(tcell.NewHexColor(0x002b36)).Bold(true),
+		Button:           tcell.StyleDefault.Foreground(tcell.NewHexColor(0xb58900)).Background(tcell.NewHexColor(0x002b36)),
+		ButtonHover:      tcell.StyleDefault.Foreground(tcell.NewHexColor(0xcb4b16)).Background(tcell.NewHexColor(0x073642)),
+		ButtonPressed:    tcell.StyleDefault.Foreground(tcell.NewHexColor(0x002b36)).Background(tcell.NewHexColor(0xcb4b16)),
+		ButtonDisabled:   tcell.StyleDefault.Foreground(tcell.NewHexColor(0x586e75)).Background(tcell.NewHexColor(0x002b36)),
+		Splitter:         tcell.StyleDefault.Foreground(tcell.NewHexColor(0x586e75)).Background(tcell.NewHexColor(0x002b36)),
+		SplitterFocused:  tcell.StyleDefault.Foreground(tcell.NewHexColor(0x268bd2)).Background(tcell.NewHexColor(0x002b36)),
+		SplitterDragging: tcell.StyleDefault.Foreground(tcell.NewHexColor(0x2aa198)).Background(tcell.NewHexColor(0x002b36)),
+		Overlay:          tcell.StyleDefault.Foreground(tcell.NewHexColor(0x93a1a1)).Background(tcell.NewHexColor(0x073642)),
+		OverlaySelected:  tcell.StyleDefault.Foreground(tcell.NewHexColor(0x002b36)).Background(tcell.NewHexColor(0x268bd2)),
+	}
+}
+
+// HighContrastTheme returns a theme which uses bright colors on black for
+// maximum readability
+func HighContrastTheme() *Theme {
+	return &Theme{
+		Background:       tcell.ColorBlack,
+		Border:           tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		BorderFocused:    tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack).Bold(true),
+		BorderUrgent:     tcell.StyleDefault.Foreground(tcell.ColorRed).Background(tcell.ColorBlack).Bold(true),
+		Title:            tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		TitleFocused:     tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow).Bold(true),
+		TitleUrgent:      tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorRed).Bold(true),
+		Button:           tcell.StyleDefault.Foreground(tcell.ColorAqua).Background(tcell.ColorBlack),
+		ButtonHover:      tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorAqua),
+		ButtonPressed:    tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorWhite),
+		ButtonDisabled:   tcell.StyleDefault.Foreground(tcell.ColorGray).Background(tcell.ColorBlack),
+		Splitter:         tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack),
+		SplitterFocused:  tcell.StyleDefault.Foreground(tcell.ColorYellow).Background(tcell.ColorBlack),
+		SplitterDragging: tcell.StyleDefault.Foreground(tcell.ColorAqua).Background(tcell.ColorBlack).Bold(true),
+		Overlay:          tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorNavy),
+		OverlaySelected:  tcell.StyleDefault.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow),
+	}
+}
+
+// Themes lists the constructors of the built-in themes by name, each call
+// returns a new theme which may be changed freely
+var Themes = map[string]func() *Theme{
+	"default":        DefaultTheme,
+	"monochrome":     MonochromeTheme,
+	"solarized-dark": SolarizedDarkTheme,
+	"high-contrast":  HighContrastTheme,
+}
+
+// inheritTheme passes the theme down the primitive tree to every layout and
+// window which does not have a theme of its own
+func inheritTheme(p tview.Primitive, theme *Theme) {
+	switch p := p.(type) {
+	case *Layout:
+		p.inheritedTheme = theme
+		if p.theme != nil {
+			theme = p.theme
+		}
+		for _, item := range p.items {
+			inheritTheme(item.Primitive, theme)
+		}
+	case *Window:
+		p.inheritedTheme = theme
+	}
+}
+
+// styledPrint prints the text like tview.Print using the foreground of the
+// style and applies the background and attributes of the style to the printed
+// cells. The text is printed off-screen first, so that each cell is set on the
+// screen once and a transforming screen (see StyleScreen) transforms it once.
+// It returns the same values as tview.Print.
+func styledPrint(screen tcell.Screen, text string, x, y, maxWidth, align int, style tcell.Style) (int, int) {
+	if maxWidth <= 0 {
+		return 0, 0
+	}
+
+	fg, bg, attr := style.Decompose()
+	line := NewBuffer(maxWidth, 1)
+	printed, width := tview.Print(line, text, 0, 0, maxWidth, align, fg)
+
+	start := 0
+	switch align {
+	case tview.AlignCenter:
+		start = (maxWidth - width) / 2
+	case tview.AlignRight:
+		start = maxWidth - width
+	}
+
+	// from right to left like tview.Print, so that the cells covered by a
+	// wide character are set before it
+	for i := start + width - 1; i >= start; i-- {
+		mainc, combc, cellStyle, _ := line.GetContent(i, 0)
+		cellFg, cellBg, _ := cellStyle.Decompose()
+		if bg != tcell.ColorDefault {
+			cellBg = bg
+		}
+		if cellBg == tcell.ColorDefault {
+			_, _, screenStyle, _ := screen.GetContent(x+i, y)
+			_, cellBg, _ = screenStyle.Decompose()
+		}
+		screen.SetContent(x+i, y, mainc, combc, tcell.StyleDefault.Foreground(cellFg).Background(cellBg).Attributes(attr))
+	}
+
+	return printed, width
+}
@@ -0,0 +1,74 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestThemesAreCopies(t *testing.T) {
+	for name, theme := range Themes {
+		changed := theme()
+		changed.Border = changed.Border.Foreground(tcell.ColorPink).Blink(true)
+
+		if theme().Border == changed.Border || theme() == theme() {
+			t.Errorf("the %s theme returns a shared theme", name)
+		}
+	}
+}
+
+func TestThemeInheritance(t *testing.T) {
+	manager := HighContrastTheme()
+	layout := SolarizedDarkTheme()
+	window := MonochromeTheme()
+	window.Border = window.Border.Foreground(tcell.ColorGreen)
+
+	windows := []*Window{
+		NewWindow().SetRoot(tview.NewBox()).SetBorder(true),
+		NewWindow().SetRoot(tview.NewBox()).SetBorder(true),
+		NewWindow().SetRoot(tview.NewBox()).SetBorder(true).SetTheme(window),
+	}
+	root := NewLayout().SetDirection(HorizontalLayout).
+		AddItemWeight(windows[0], 1).
+		AddItemWeight(NewLayout().SetTheme(layout).
+			AddItemWeight(windows[1], 1).
+			AddItemWeight(windows[2], 1), 1)
+
+	m := NewWindowManager().SetRoot(root).SetTheme(manager)
+	m.SetRect(0, 0, 40, 10)
+	screen := newSimulationScreen(t, 40, 10)
+	m.Draw(screen)
+
+	for i, want := range []*Theme{manager, layout, window} {
+		x, y, _, _ := windows[i].GetRect()
+		if got := cellStyle(screen, x, y); got != want.Border {
+			t.Errorf("the border of window %d is drawn with %v, want %v", i, got, want.Border)
+		}
+	}
+}
+
+func TestCurrentThemeCached(t *testing.T) {
+	w := NewWindow()
+	w.SetBorderColor(tcell.ColorRed)
+
+	theme := w.currentTheme()
+	if fg, _, _ := theme.Border.Decompose(); fg != tcell.ColorRed {
+		t.Errorf("the border color is %v, want red", fg)
+	}
+	if w.currentTheme() != theme {
+		t.Error("the theme was made again without any change")
+	}
+
+	w.SetBorderColor(tcell.ColorBlue)
+	if fg, _, _ := w.currentTheme().Border.Decompose(); fg != tcell.ColorBlue {
+		t.Errorf("the border color is %v after the change, want blue", fg)
+	}
+
+	styles := tview.Styles
+	defer func() { tview.Styles = styles }()
+	tview.Styles.ContrastBackgroundColor = tcell.ColorPurple
+	if _, bg, _ := w.currentTheme().Overlay.Decompose(); bg != tcell.ColorPurple {
+		t.Errorf("the overlay background is %v after tview.Styles changed, want purple", bg)
+	}
+}
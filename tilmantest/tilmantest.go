@@ -0,0 +1,415 @@
+// Package tilmantest mounts a window manager on a simulated screen to test
+// layouts without a terminal. It injects key and mouse events like a
+// tview.Application and returns the rendering for assertions:
+//
+//   h := tilmantest.New(t, manager, 80, 24)
+//   h.DragSplitter(layout, 0, 30)
+//   h.ClickButton(window, 0)
+//   h.AssertGolden(t, "maximized")
+//   h.AssertGoldenStyled(t, "maximized")
+//
+// Events recorded from a running application (see tilman.Recorder) are
+// replayed with Replay to turn a bug report into a regression test.
+package tilmantest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axard/tilman"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// UpdateGoldenEnv is the environment variable which makes AssertGolden write
+// the golden files instead of comparing them, e.g.
+//
+//   TILMAN_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "TILMAN_UPDATE_GOLDEN"
+
+// Cell is a cell of the simulated screen
+type Cell struct {
+	Rune      rune
+	Combining []rune
+	Style     tcell.Style
+}
+
+// Harness drives a window manager on a simulated screen
+type Harness struct {
+	Screen  tcell.SimulationScreen
+	Manager *tilman.Manager
+
+	focus   tview.Primitive
+	capture tview.Primitive
+}
+
+// New creates a simulated screen of the given size, mounts the manager on it
+// with focus and draws it. It fails the test if the screen cannot be
+// initialized.
+func New(t testing.TB, manager *tilman.Manager, width, height int) *Harness {
+	t.Helper()
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(width, height)
+
+	h := &Harness{
+		Screen:  screen,
+		Manager: manager,
+	}
+	h.SetFocus(manager)
+	h.Draw()
+
+	return h
+}
+
+// SetFocus gives the focus to the primitive like tview.Application.SetFocus
+func (h *Harness) SetFocus(p tview.Primitive) {
+	if h.focus != nil {
+		h.focus.Blur()
+	}
+	h.focus = p
+	if p != nil {
+		p.Focus(h.SetFocus)
+	}
+}
+
+// GetFocus returns the primitive which has focus
+func (h *Harness) GetFocus() tview.Primitive {
+	return h.focus
+}
+
+// Draw draws the manager over the whole screen
+func (h *Harness) Draw() {
+	width, height := h.Screen.Size()
+	h.Manager.SetRect(0, 0, width, height)
+	h.Manager.Draw(h.Screen)
+	h.Screen.Show()
+}
+
+// Resize changes the size of the screen and redraws
+func (h *Harness) Resize(width, height int) {
+	h.Screen.SetSize(width, height)
+	h.Draw()
+}
+
+// Key sends a key event to the manager and redraws
+func (h *Harness) Key(key tcell.Key, r rune, modifiers tcell.ModMask) {
+	if handler := h.Manager.InputHandler(); handler != nil {
+		handler(tcell.NewEventKey(key, r, modifiers), h.SetFocus)
+	}
+	h.Draw()
+}
+
+// Press sends the key combination in the format of tilman.ParseKeyBinding,
+// e.g. "Alt+M"
+func (h *Harness) Press(key string) error {
+	binding, err := tilman.ParseKeyBinding(key)
+	if err != nil {
+		return err
+	}
+
+	h.Key(binding.Key, binding.Rune, binding.Modifiers)
+	return nil
+}
+
+// Type sends the characters of the text as key events
+func (h *Harness) Type(text string) {
+	for _, r := range text {
+		h.Key(tcell.KeyRune, r, tcell.ModNone)
+	}
+}
+
+// Mouse sends a mouse event to the manager, or to the primitive which
+// captured the mouse, and redraws
+func (h *Harness) Mouse(action tview.MouseAction, x, y int, buttons tcell.ButtonMask) {
+	h.mouse(action, tcell.NewEventMouse(x, y, buttons, tcell.ModNone))
+}
+
+func (h *Harness) mouse(action tview.MouseAction, event *tcell.EventMouse) {
+	target := tview.Primitive(h.Manager)
+	if h.capture != nil {
+		target = h.capture
+	}
+
+	if handler := target.MouseHandler(); handler != nil {
+		_, h.capture = handler(action, event, h.SetFocus)
+	}
+	h.Draw()
+}
+
+// Click clicks the left mouse button at the given position
+func (h *Harness) Click(x, y int) {
+	h.Mouse(tview.MouseLeftDown, x, y, tcell.Button1)
+	h.Mouse(tview.MouseLeftUp, x, y, tcell.ButtonNone)
+	h.Mouse(tview.MouseLeftClick, x, y, tcell.ButtonNone)
+}
+
+// RightClick clicks the right mouse button at the given position
+func (h *Harness) RightClick(x, y int) {
+	h.Mouse(tview.MouseRightDown, x, y, tcell.Button2)
+	h.Mouse(tview.MouseRightUp, x, y, tcell.ButtonNone)
+	h.Mouse(tview.MouseRightClick, x, y, tcell.ButtonNone)
+}
+
+// Drag presses the left mouse button at the first position, moves the mouse
+// to the second one cell by cell and releases the button there
+func (h *Harness) Drag(fromX, fromY, toX, toY int) {
+	h.Mouse(tview.MouseLeftDown, fromX, fromY, tcell.Button1)
+
+	x, y := fromX, fromY
+	for x != toX || y != toY {
+		x += sign(toX - x)
+		y += sign(toY - y)
+		h.Mouse(tview.MouseMove, x, y, tcell.Button1)
+	}
+
+	h.Mouse(tview.MouseLeftUp, toX, toY, tcell.ButtonNone)
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// DragSplitter drags the i-th splitter of the layout to the given column, or
+// row for the splitters of a vertical layout. It returns false if the layout
+// has no such splitter.
+func (h *Harness) DragSplitter(layout *tilman.Layout, i, to int) bool {
+	x, y, ok := layout.GetSplitterPosition(i)
+	if !ok {
+		return false
+	}
+
+	if layout.GetDirection() == tilman.HorizontalLayout {
+		h.Drag(x, y, to, y)
+	} else {
+		h.Drag(x, y, x, to)
+	}
+	return true
+}
+
+// ClickButton clicks the i-th title bar button of the window. It returns
+// false if the button is not shown.
+func (h *Harness) ClickButton(window *tilman.Window, i int) bool {
+	x, y, _, ok := window.GetButtonRect(i)
+	if !ok {
+		return false
+	}
+
+	h.Click(x, y)
+	return true
+}
+
+// Replay sends the events recorded by a tilman.Recorder to the manager in
+// their order, without waiting between them, so that replaying them always
+// produces the same layout. The mouse positions are translated from the origin
+// of the recorded manager to the origin of this one. Resize events resize the
+// screen, the recording is expected to come from a manager filling the screen.
+func (h *Harness) Replay(events []tilman.RecordedEvent) {
+	for _, event := range events {
+		h.replay(event)
+	}
+}
+
+// ReplayTimed is Replay waiting between the events as long as they were apart
+// when recorded, divided by the speed: 2 replays twice as fast. It reproduces
+// what depends on the timing, such as the tooltips of buttons. A speed of zero
+// or less does not wait, like Replay.
+func (h *Harness) ReplayTimed(events []tilman.RecordedEvent, speed float64) {
+	if speed <= 0 {
+		h.Replay(events)
+		return
+	}
+
+	start := time.Now()
+	for _, event := range events {
+		if wait := time.Duration(float64(event.Time)/speed) - time.Since(start); wait > 0 {
+			time.Sleep(wait)
+		}
+		h.replay(event)
+	}
+}
+
+// replay sends a recorded event to the manager
+func (h *Harness) replay(event tilman.RecordedEvent) {
+	switch event.Kind {
+	case tilman.EventKey:
+		h.Key(event.Key, event.Rune, event.Modifiers)
+	case tilman.EventMouse:
+		x, y, _, _ := h.Manager.GetRect()
+		h.mouse(event.Action, tcell.NewEventMouse(x+event.X, y+event.Y, event.Buttons, event.Modifiers))
+	case tilman.EventResize:
+		h.Resize(event.Width, event.Height)
+	}
+}
+
+// ReplayFile replays the events recorded to the file
+func (h *Harness) ReplayFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	events, err := tilman.ReadEvents(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	h.Replay(events)
+	return nil
+}
+
+// Cells returns the cells of the screen by row
+func (h *Harness) Cells() [][]Cell {
+	contents, width, height := h.Screen.GetContents()
+
+	rows := make([][]Cell, height)
+	for y := range rows {
+		rows[y] = make([]Cell, width)
+		for x := range rows[y] {
+			content := contents[y*width+x]
+			cell := Cell{Rune: ' ', Style: content.Style}
+			if len(content.Runes) > 0 {
+				cell.Rune, cell.Combining = content.Runes[0], content.Runes[1:]
+			}
+			rows[y][x] = cell
+		}
+	}
+
+	return rows
+}
+
+// Row returns the text of the given row of the screen, trailing spaces
+// removed
+func (h *Harness) Row(y int) string {
+	contents, width, height := h.Screen.GetContents()
+	if y < 0 || y >= height {
+		return ""
+	}
+
+	var b strings.Builder
+	for x := 0; x < width; x++ {
+		content := contents[y*width+x]
+		if len(content.Runes) == 0 {
+			// covered by a wide character
+			continue
+		}
+		b.WriteString(string(content.Runes))
+	}
+
+	return strings.TrimRight(b.String(), " ")
+}
+
+// Text returns the text of the screen, one line per row with trailing spaces
+// removed
+func (h *Harness) Text() string {
+	_, height := h.Screen.Size()
+
+	var b strings.Builder
+	for y := 0; y < height; y++ {
+		b.WriteString(h.Row(y))
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Contains returns true if the text is shown on a row of the screen
+func (h *Harness) Contains(text string) bool {
+	_, height := h.Screen.Size()
+	for y := 0; y < height; y++ {
+		if strings.Contains(h.Row(y), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capture returns the screen in the given format, see tilman.Buffer.Capture
+func (h *Harness) Capture(format tilman.CaptureFormat) string {
+	contents, width, height := h.Screen.GetContents()
+
+	buffer := tilman.NewBuffer(width, height)
+	for i, content := range contents {
+		if len(content.Runes) > 0 {
+			buffer.SetContent(i%width, i/width, content.Runes[0], content.Runes[1:], content.Style)
+		}
+	}
+
+	return buffer.Capture(format)
+}
+
+// AssertGolden compares the text of the screen with the golden file
+// testdata/<name>.golden and fails the test if they differ. With the
+// environment variable TILMAN_UPDATE_GOLDEN set, the file is written instead.
+func (h *Harness) AssertGolden(t testing.TB, name string) {
+	t.Helper()
+
+	assertGolden(t, filepath.Join("testdata", name+".golden"), h.Text(), false)
+}
+
+// AssertGoldenStyled is AssertGolden with the colors and attributes: the
+// screen is captured with ANSI escape sequences (see tilman.CaptureANSI) and
+// compared with testdata/<name>.ans.golden
+func (h *Harness) AssertGoldenStyled(t testing.TB, name string) {
+	t.Helper()
+
+	assertGolden(t, filepath.Join("testdata", name+".ans.golden"), h.Capture(tilman.CaptureANSI), true)
+}
+
+// assertGolden compares the screen with the golden file or writes it, the
+// escape sequences of styled screens are quoted in the failure message
+func assertGolden(t testing.TB, path, screen string, styled bool) {
+	t.Helper()
+
+	actual := []byte(screen)
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (set %s=1 to create it)", err, UpdateGoldenEnv)
+	}
+	if bytes.Equal(actual, expected) {
+		return
+	}
+
+	if !styled {
+		t.Errorf("screen differs from %s\n--- expected\n%s--- actual\n%s", path, expected, actual)
+		return
+	}
+	expectedLines := strings.Split(string(expected), "\n")
+	actualLines := strings.Split(screen, "\n")
+	for i := range actualLines {
+		if i >= len(expectedLines) || actualLines[i] != expectedLines[i] {
+			var want string
+			if i < len(expectedLines) {
+				want = expectedLines[i]
+			}
+			t.Errorf("styled screen differs from %s on row %d\n--- expected\n%q\n--- actual\n%q", path, i, want, actualLines[i])
+			return
+		}
+	}
+	t.Errorf("styled screen differs from %s, it has %d rows, want %d", path, len(actualLines), len(expectedLines))
+}
@@ -0,0 +1,345 @@
+package tilmantest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/axard/tilman"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// fixture is a manager with two windows side by side, the right one has a
+// button counting its clicks, an input field and a vertical scrollbar
+type fixture struct {
+	*Harness
+
+	layout      *tilman.Layout
+	left, right *tilman.Window
+	input       *tview.InputField
+	clicks      int
+}
+
+// newFixture creates the fixture, its manager records the events if the
+// recorder is not nil
+func newFixture(t testing.TB, recorder *tilman.Recorder) *fixture {
+	f := &fixture{
+		input: tview.NewInputField(),
+	}
+
+	f.left = tilman.NewWindow().SetRoot(tview.NewTextView().SetText("left")).SetTitle("Left").SetBorder(true)
+	f.right = tilman.NewWindow().SetRoot(f.input).SetTitle("Right").SetBorder(true).SetVirtualSize(10, 40).
+		AddButton('x', tilman.WindowButtonAlignRight, func(w *tilman.Window, b *tilman.WindowButton) {
+			f.clicks++
+		})
+	f.layout = tilman.NewLayout().
+		SetDirection(tilman.HorizontalLayout).
+		SetSplitter(true).
+		AddItemWeight(f.left, 1).
+		AddItemWeight(f.right, 1)
+
+	manager := tilman.NewWindowManager().
+		SetRoot(f.layout).
+		SetKeybinding(tilman.ActionFocusNext, tilman.KeyBinding{Key: tcell.KeyCtrlN}).
+		SetRecorder(recorder)
+	f.Harness = New(t, manager, 40, 8)
+	f.SetFocus(f.left)
+
+	return f
+}
+
+// splitterColumn returns the column of the splitter between the windows
+func (f *fixture) splitterColumn(t *testing.T) int {
+	x, _, ok := f.layout.GetSplitterPosition(0)
+	if !ok {
+		t.Fatal("the layout has no splitter")
+	}
+	return x
+}
+
+func TestKey(t *testing.T) {
+	f := newFixture(t, nil)
+
+	f.Key(tcell.KeyCtrlN, 0, tcell.ModNone)
+	if !f.right.HasFocus() {
+		t.Fatal("Ctrl+N did not focus the right window")
+	}
+
+	f.Type("hi")
+	if f.input.GetText() != "hi" || !f.Contains("hi") {
+		t.Errorf("typed text is %q, screen:\n%s", f.input.GetText(), f.Text())
+	}
+
+	if err := f.Press("Ctrl+N"); err != nil {
+		t.Fatal(err)
+	}
+	if !f.left.HasFocus() {
+		t.Error("Press(\"Ctrl+N\") did not focus the left window")
+	}
+	if err := f.Press("Hyper+N"); err == nil {
+		t.Error("Press accepted an invalid key")
+	}
+}
+
+func TestMouse(t *testing.T) {
+	f := newFixture(t, nil)
+
+	// the input field takes the focus when its row is clicked
+	x, y, _, _ := f.right.GetRect()
+	f.Mouse(tview.MouseLeftClick, x+2, y+1, tcell.ButtonNone)
+	if !f.right.HasFocus() {
+		t.Error("a click on the input field did not focus the right window")
+	}
+
+	x, y, _, _ = f.left.GetRect()
+	f.Click(x+2, y+2)
+	if !f.left.HasFocus() {
+		t.Error("Click did not focus the left window")
+	}
+}
+
+func TestDrag(t *testing.T) {
+	f := newFixture(t, nil)
+
+	from := f.splitterColumn(t)
+	f.Drag(from, 3, from-5, 3)
+	if got := f.splitterColumn(t); got != from-5 {
+		t.Errorf("the splitter is at %d after dragging it from %d to %d", got, from, from-5)
+	}
+
+	f.Drag(f.splitterColumn(t), 3, 30, 5)
+	if got := f.splitterColumn(t); got != 30 {
+		t.Errorf("the splitter is at %d after dragging it down to 30, want 30", got)
+	}
+}
+
+func TestDragSplitter(t *testing.T) {
+	f := newFixture(t, nil)
+
+	if !f.DragSplitter(f.layout, 0, 25) {
+		t.Fatal("DragSplitter did not find the splitter")
+	}
+	if got := f.splitterColumn(t); got != 25 {
+		t.Errorf("the splitter is at %d, want 25", got)
+	}
+	if f.DragSplitter(f.layout, 1, 10) {
+		t.Error("DragSplitter found a second splitter")
+	}
+}
+
+func TestClickButton(t *testing.T) {
+	f := newFixture(t, nil)
+
+	if !f.ClickButton(f.right, 0) || !f.ClickButton(f.right, 0) {
+		t.Fatal("ClickButton did not find the button")
+	}
+	if f.clicks != 2 {
+		t.Errorf("the button was clicked %d times, want 2", f.clicks)
+	}
+	if f.ClickButton(f.left, 0) {
+		t.Error("ClickButton found a button on the left window")
+	}
+}
+
+// fakeT records the failures of an assertion instead of failing the test
+type fakeT struct {
+	testing.TB
+
+	failures []string
+	fatal    bool
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatal(args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprint(args...))
+	f.fatal = true
+	runtime.Goexit()
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.Fatal(fmt.Sprintf(format, args...))
+}
+
+// assert runs the assertion in its own goroutine, which a fatal failure ends
+func assert(assertion func(t testing.TB)) *fakeT {
+	f := &fakeT{}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assertion(f)
+	}()
+	<-done
+
+	return f
+}
+
+// inTempDir runs the test in an empty working directory
+func inTempDir(t *testing.T) string {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := inTempDir(t)
+	f := newFixture(t, nil)
+
+	failed := assert(func(t testing.TB) { f.AssertGolden(t, "screen") })
+	if !failed.fatal || !strings.Contains(failed.failures[0], UpdateGoldenEnv) {
+		t.Errorf("a missing golden file failed with %q", failed.failures)
+	}
+
+	os.Setenv(UpdateGoldenEnv, "1")
+	updated := assert(func(t testing.TB) { f.AssertGolden(t, "screen") })
+	os.Unsetenv(UpdateGoldenEnv)
+	if len(updated.failures) > 0 {
+		t.Fatalf("updating failed with %q", updated.failures)
+	}
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "screen.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(golden) != f.Text() {
+		t.Errorf("the golden file is\n%s\nwant\n%s", golden, f.Text())
+	}
+
+	if passed := assert(func(t testing.TB) { f.AssertGolden(t, "screen") }); len(passed.failures) > 0 {
+		t.Errorf("the same screen failed with %q", passed.failures)
+	}
+
+	f.DragSplitter(f.layout, 0, 10)
+	mismatch := assert(func(t testing.TB) { f.AssertGolden(t, "screen") })
+	if len(mismatch.failures) != 1 || mismatch.fatal || !strings.Contains(mismatch.failures[0], "screen differs") {
+		t.Errorf("a changed screen failed with %q", mismatch.failures)
+	}
+}
+
+func TestAssertGoldenStyled(t *testing.T) {
+	dir := inTempDir(t)
+	f := newFixture(t, nil)
+
+	os.Setenv(UpdateGoldenEnv, "1")
+	updated := assert(func(t testing.TB) {
+		f.AssertGolden(t, "screen")
+		f.AssertGoldenStyled(t, "screen")
+	})
+	os.Unsetenv(UpdateGoldenEnv)
+	if len(updated.failures) > 0 {
+		t.Fatalf("updating failed with %q", updated.failures)
+	}
+	golden, err := os.ReadFile(filepath.Join(dir, "testdata", "screen.ans.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(golden) != f.Capture(tilman.CaptureANSI) || !strings.Contains(string(golden), "\x1b[") {
+		t.Errorf("the styled golden file is %q", golden)
+	}
+
+	// a change of colors only fails the styled assertion
+	f.input.SetFieldBackgroundColor(tcell.NewRGBColor(255, 0, 0))
+	f.Draw()
+	if passed := assert(func(t testing.TB) { f.AssertGolden(t, "screen") }); len(passed.failures) > 0 {
+		t.Errorf("the text assertion failed with %q", passed.failures)
+	}
+	mismatch := assert(func(t testing.TB) { f.AssertGoldenStyled(t, "screen") })
+	if len(mismatch.failures) != 1 || !strings.Contains(mismatch.failures[0], "styled screen differs") {
+		t.Errorf("a recolored screen failed with %q", mismatch.failures)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var recording bytes.Buffer
+	recorded := newFixture(t, tilman.NewRecorder(&recording))
+
+	recorded.DragSplitter(recorded.layout, 0, 25)
+	x, y, width, _ := recorded.right.GetRect()
+	recorded.Click(x+2, y+1)
+	recorded.Type("replayed")
+	recorded.ClickButton(recorded.right, 0)
+	if recorded.clicks != 1 || recorded.input.GetText() != "replayed" {
+		t.Fatalf("recorded %d clicks and the text %q", recorded.clicks, recorded.input.GetText())
+	}
+
+	// the scrollbar captures the mouse, the events below the screen only
+	// reach it through the manager
+	recorded.Drag(x+width-1, y+1, x+width-1, 20)
+	if _, offset := recorded.right.GetScrollOffset(); offset == 0 {
+		t.Fatal("dragging the scrollbar did not scroll")
+	}
+
+	recorded.Resize(50, 10)
+	recorded.DragSplitter(recorded.layout, 0, 12)
+
+	if err := recorded.Manager.GetRecorder().Err(); err != nil {
+		t.Fatal(err)
+	}
+	events, err := tilman.ReadEvents(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := newFixture(t, nil)
+	replayed.Replay(events)
+
+	if replayed.Text() != recorded.Text() {
+		t.Errorf("replayed screen\n%s\nrecorded screen\n%s", replayed.Text(), recorded.Text())
+	}
+	if got, want := replayed.splitterColumn(t), recorded.splitterColumn(t); got != want {
+		t.Errorf("the replayed splitter is at %d, want %d", got, want)
+	}
+	gotX, gotY := replayed.right.GetScrollOffset()
+	wantX, wantY := recorded.right.GetScrollOffset()
+	if gotX != wantX || gotY != wantY {
+		t.Errorf("the replayed scroll offset is %d,%d, want %d,%d", gotX, gotY, wantX, wantY)
+	}
+	if replayed.clicks != recorded.clicks || replayed.input.GetText() != recorded.input.GetText() {
+		t.Errorf("replayed %d clicks and the text %q, want %d and %q",
+			replayed.clicks, replayed.input.GetText(), recorded.clicks, recorded.input.GetText())
+	}
+}
+
+func TestReplayTimed(t *testing.T) {
+	events := []tilman.RecordedEvent{
+		{Time: 0, Kind: tilman.EventKey, Key: tcell.KeyCtrlN},
+		{Time: 40 * time.Millisecond, Kind: tilman.EventKey, Key: tcell.KeyRune, Rune: 'a'},
+		{Time: 80 * time.Millisecond, Kind: tilman.EventKey, Key: tcell.KeyRune, Rune: 'b'},
+	}
+
+	for _, test := range []struct {
+		speed   float64
+		minimum time.Duration
+	}{
+		{speed: 2, minimum: 40 * time.Millisecond},
+		{speed: 0},
+	} {
+		f := newFixture(t, nil)
+		start := time.Now()
+		f.ReplayTimed(events, test.speed)
+		if elapsed := time.Since(start); elapsed < test.minimum {
+			t.Errorf("the replay at speed %v took %v, want at least %v", test.speed, elapsed, test.minimum)
+		}
+		if !f.right.HasFocus() || f.input.GetText() != "ab" {
+			t.Errorf("the replay at speed %v typed %q", test.speed, f.input.GetText())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package tilman
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// TitleTruncation defines how a title too long for the title bar is shortened
+type TitleTruncation int
+
+const (
+	// TruncateEnd cuts the end of the title and ends it with an ellipsis
+	TruncateEnd TitleTruncation = iota
+	// TruncateMiddle replaces the middle of the title with an ellipsis, keeping
+	// both ends visible, e.g. "/home/…/main.go"
+	TruncateMiddle
+)
+
+// SetTitleColor sets the color of the title of windows without theme
+func (w *Window) SetTitleColor(color tcell.Color) *Window {
+	w.titleColor = color
+	return w
+}
+
+// GetTitleColor returns the color of the title of windows without theme
+func (w *Window) GetTitleColor() tcell.Color {
+	return w.titleColor
+}
+
+// SetTitleAlign sets the alignment of the title, one of tview.AlignLeft,
+// tview.AlignCenter or tview.AlignRight
+func (w *Window) SetTitleAlign(align int) *Window {
+	w.titleAlign = align
+	return w
+}
+
+// GetTitleAlign returns the alignment of the title
+func (w *Window) GetTitleAlign() int {
+	return w.titleAlign
+}
+
+// SetTitleFunc sets a function which provides the title each time the window
+// is drawn, e.g. the name of the edited file with a "[+]" modified marker.
+// It overrides the title set with SetTitle, nil removes it.
+func (w *Window) SetTitleFunc(provider func(w *Window) string) *Window {
+	w.titleFunc = provider
+	return w
+}
+
+// GetTitle returns the title of the window, as provided by the title function
+// if any
+func (w *Window) GetTitle() string {
+	if w.titleFunc != nil {
+		return w.titleFunc(w)
+	}
+	return w.Box.GetTitle()
+}
+
+// SetTitleTruncation sets how a title too long for the title bar is shortened
+func (w *Window) SetTitleTruncation(truncation TitleTruncation) *Window {
+	w.titleTruncation = truncation
+	return w
+}
+
+// GetTitleTruncation returns how a title too long for the title bar is shortened
+func (w *Window) GetTitleTruncation() TitleTruncation {
+	return w.titleTruncation
+}
+
+// titleToken is a character or a color tag of a title, tags take no space
+type titleToken struct {
+	text  string
+	width int
+	tag   bool
+}
+
+// tokenizeTitle splits a title with color tags into tags and characters. The
+// tags are parsed by tview: the title is split where the widths of both parts
+// add up to its width, a cut inside a tag or an escaped bracket would print
+// them as text.
+func tokenizeTitle(title string) []titleToken {
+	var tokens []titleToken
+
+	add := func(text string) {
+		width := tview.TaggedStringWidth(text)
+		tag := width == 0 && strings.HasPrefix(text, "[")
+		if width == 0 && !tag && len(tokens) > 0 && !tokens[len(tokens)-1].tag {
+			// combining characters stay with their base character
+			tokens[len(tokens)-1].text += text
+			return
+		}
+		tokens = append(tokens, titleToken{text: text, width: width, tag: tag})
+	}
+
+	total := tview.TaggedStringWidth(title)
+	start := 0
+	for i := range title {
+		if i > start && tview.TaggedStringWidth(title[:i])+tview.TaggedStringWidth(title[i:]) == total {
+			add(title[start:i])
+			start = i
+		}
+	}
+	if start < len(title) {
+		add(title[start:])
+	}
+
+	return tokens
+}
+
+// truncateEnd shortens the title to the given width by replacing its end with
+// an ellipsis, which has the color of the last character kept
+func truncateEnd(title string, width int) string {
+	if width < 1 || tview.TaggedStringWidth(title) <= width {
+		return title
+	}
+
+	// the tags are kept with the character following them
+	var b strings.Builder
+	tags, used := "", 0
+	for _, token := range tokenizeTitle(title) {
+		if token.tag {
+			tags += token.text
+			continue
+		}
+		if used+token.width > width-1 {
+			break
+		}
+		used += token.width
+		b.WriteString(tags + token.text)
+		tags = ""
+	}
+	b.WriteRune(tview.SemigraphicsHorizontalEllipsis)
+
+	return b.String()
+}
+
+// truncateMiddle shortens the title to the given width by replacing its middle
+// with an ellipsis. The color tags of the removed part are kept so that the
+// end of the title has its colors.
+func truncateMiddle(title string, width int) string {
+	if width < 1 || tview.TaggedStringWidth(title) <= width {
+		return title
+	}
+
+	tokens := tokenizeTitle(title)
+
+	// the width left for both ends of the title, the head gets the odd cell
+	available := width - 1
+	tailWidth := available / 2
+	headWidth := available - tailWidth
+
+	head, used := 0, 0
+	for head < len(tokens) && used+tokens[head].width <= headWidth {
+		used += tokens[head].width
+		head++
+	}
+
+	tail, used := len(tokens), 0
+	for tail > head && used+tokens[tail-1].width <= tailWidth {
+		used += tokens[tail-1].width
+		tail--
+	}
+
+	var b strings.Builder
+	for _, token := range tokens[:head] {
+		b.WriteString(token.text)
+	}
+	b.WriteRune(tview.SemigraphicsHorizontalEllipsis)
+	for _, token := range tokens[head:tail] {
+		if token.tag {
+			b.WriteString(token.text)
+		}
+	}
+	for _, token := range tokens[tail:] {
+		b.WriteString(token.text)
+	}
+
+	return b.String()
+}
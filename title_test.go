@@ -0,0 +1,111 @@
+package tilman
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+func TestTokenizeTitle(t *testing.T) {
+	tokens := tokenizeTitle("[red]ab[::b]c[x[]éa[b")
+
+	want := []titleToken{
+		{text: "[red]", tag: true},
+		{text: "a", width: 1},
+		{text: "b", width: 1},
+		{text: "[::b]", tag: true},
+		{text: "c", width: 1},
+		{text: "[x[]", width: 3},
+		{text: "é", width: 1},
+		{text: "a", width: 1},
+		{text: "[", width: 1},
+		{text: "b", width: 1},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("the tokens are %+v, want %+v", tokens, want)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %d is %+v, want %+v", i, tokens[i], want[i])
+		}
+	}
+}
+
+func TestTruncateEnd(t *testing.T) {
+	tests := []struct {
+		title string
+		width int
+		want  string
+	}{
+		{"/home/user/src/main.go", 11, "/home/user…"},
+		{"/home/user/src/main.go", 22, "/home/user/src/main.go"},
+		{"[red]abc[blue]def", 4, "[red]abc…"},
+		{"ab[x[]defghi", 6, "ab[x[]…"},
+		{"日本語の文章", 6, "日本…"},
+		{"abcdef", 1, "…"},
+	}
+
+	for _, test := range tests {
+		if got := truncateEnd(test.title, test.width); got != test.want {
+			t.Errorf("%q truncated to %d is %q, want %q", test.title, test.width, got, test.want)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		title string
+		width int
+		want  string
+	}{
+		{"/home/user/src/main.go", 11, "/home…in.go"},
+		{"/home/user/src/main.go", 22, "/home/user/src/main.go"},
+		{"[red]abcdef[blue]ghijkl", 7, "[red]abc…[blue]jkl"},
+		{"abc[x[]defghi", 6, "abc…hi"},
+		{"ab[x[]defghi", 10, "ab[x[]…fghi"},
+		{"日本語の文章", 7, "日…章"},
+		{"abcdef", 1, "…"},
+	}
+
+	for _, test := range tests {
+		if got := truncateMiddle(test.title, test.width); got != test.want {
+			t.Errorf("%q truncated to %d is %q, want %q", test.title, test.width, got, test.want)
+		}
+	}
+}
+
+func TestTitle(t *testing.T) {
+	changes := 0
+	w := NewWindow().SetBorder(true).SetBorderStyle(BorderSingle).
+		SetTitleAlign(tview.AlignLeft).
+		SetTitleFunc(func(w *Window) string {
+			changes++
+			return "[red]x[-]y"
+		})
+
+	screen := drawWindow(t, w, 10, 3)
+	if changes == 0 || w.GetTitle() != "[red]x[-]y" {
+		t.Errorf("the title function was not used, the title is %q", w.GetTitle())
+	}
+	if w.GetTitleAlign() != tview.AlignLeft {
+		t.Errorf("the title alignment is %d", w.GetTitleAlign())
+	}
+	checkCells(t, screen, "colored title", map[[2]int]rune{
+		{2, 0}: 'x',
+		{3, 0}: 'y',
+	})
+	if fg, _, _ := cellStyle(screen, 2, 0).Decompose(); fg != tcell.ColorRed {
+		t.Errorf("the tagged part of the title is drawn in %v, want red", fg)
+	}
+	if fg, _, _ := cellStyle(screen, 3, 0).Decompose(); fg == tcell.ColorRed {
+		t.Error("the part after the reset tag is drawn in red")
+	}
+
+	w.SetTitleFunc(nil).SetTitle("abcdefghijklmnop").SetTitleTruncation(TruncateMiddle)
+	screen = drawWindow(t, w, 12, 3)
+	if line := strings.Split(screenText(screen), "\n")[0]; !strings.Contains(line, "abcd…nop") {
+		t.Errorf("the title bar is %q, want the middle of the title truncated", line)
+	}
+}
@@ -0,0 +1,105 @@
+package tilman
+
+import (
+	"time"
+
+	"github.com/rivo/tview"
+)
+
+// SetUrgent marks the window as requiring attention, e.g. when a build running
+// in a background window finishes. Urgent windows are drawn with the urgent
+// styles of the theme until they receive focus. It does not lock the manager:
+// from another goroutine call it inside Manager.Update.
+func (w *Window) SetUrgent(urgent bool) *Window {
+	if urgent && !w.urgent {
+		w.urgentSince = time.Now()
+	}
+	w.urgent = urgent
+	return w
+}
+
+// IsUrgent returns true if the window requires attention
+func (w *Window) IsUrgent() bool {
+	return w.urgent
+}
+
+// SetUrgentBlink sets whether the border and title of the window blink while
+// it is urgent, if the terminal supports it
+func (w *Window) SetUrgentBlink(blink bool) *Window {
+	w.urgentBlink = blink
+	return w
+}
+
+// UrgentWindows returns the urgent windows of the manager, the oldest first
+func (m *Manager) UrgentWindows() []*Window {
+	var urgent []*Window
+	for _, w := range collectWindows(m.logicalRoot) {
+		if !w.urgent {
+			continue
+		}
+
+		i := len(urgent)
+		for i > 0 && urgent[i-1].urgentSince.After(w.urgentSince) {
+			i--
+		}
+		urgent = append(urgent, nil)
+		copy(urgent[i+1:], urgent[i:])
+		urgent[i] = w
+	}
+
+	return urgent
+}
+
+// SetUrgentFunc sets a handler called when the urgent windows changed, with
+// the urgent windows from the oldest, e.g. to ring the terminal bell. It is
+// called from Draw with the lock of the manager held, see Manager.
+func (m *Manager) SetUrgentFunc(handler func(windows []*Window)) *Manager {
+	m.urgentFunc = handler
+	return m
+}
+
+// notifyUrgent calls the urgent handler if the urgent windows changed since
+// it was last called, the caller holds the lock
+func (m *Manager) notifyUrgent() {
+	urgent := m.UrgentWindows()
+
+	changed := len(urgent) != len(m.notifiedUrgent)
+	for i := 0; !changed && i < len(urgent); i++ {
+		changed = urgent[i] != m.notifiedUrgent[i]
+	}
+	m.notifiedUrgent = urgent
+
+	if changed && m.urgentFunc != nil {
+		m.urgentFunc(urgent)
+	}
+}
+
+// FocusUrgent makes the oldest urgent window visible, restoring a maximized
+// window, and gives it the focus the next time the manager is focused, which
+// clears its flag. Usually Application.SetFocus is called with the manager
+// afterwards. It returns nil if no window is urgent.
+func (m *Manager) FocusUrgent() *Window {
+	return m.focusUrgent(nil)
+}
+
+// focusUrgent makes the oldest urgent window visible and gives it the focus
+// with setFocus, or the next time the manager is focused if setFocus is nil
+func (m *Manager) focusUrgent(setFocus func(p tview.Primitive)) *Window {
+	urgent := m.UrgentWindows()
+	if len(urgent) == 0 {
+		return nil
+	}
+
+	w := urgent[0]
+	if _, ok := m.visibleRoot.(*Window); ok && !m.IsMaximazed(w) {
+		m.Restore()
+	}
+
+	if setFocus == nil {
+		m.pendingFocus = w
+	} else {
+		setFocus(w)
+	}
+
+	return w
+}
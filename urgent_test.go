@@ -0,0 +1,109 @@
+package tilman
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newUrgentManager creates a 40x10 manager with three bordered windows side by
+// side
+func newUrgentManager() (*Manager, []*Window) {
+	var windows []*Window
+	root := NewLayout().SetDirection(HorizontalLayout)
+	for i := 0; i < 3; i++ {
+		w := NewWindow().SetRoot(tview.NewBox()).SetBorder(true)
+		root.AddItemWeight(w, 1)
+		windows = append(windows, w)
+	}
+
+	m := NewWindowManager().SetRoot(root)
+	m.SetRect(0, 0, 40, 10)
+	return m, windows
+}
+
+func TestUrgentWindows(t *testing.T) {
+	m, windows := newUrgentManager()
+
+	windows[2].SetUrgent(true)
+	windows[0].SetUrgent(true)
+	windows[0].urgentSince = windows[2].urgentSince.Add(time.Second)
+	if urgent := m.UrgentWindows(); !reflect.DeepEqual(urgent, []*Window{windows[2], windows[0]}) {
+		t.Errorf("the urgent windows are %v, want the third and the first", urgent)
+	}
+
+	screen := newSimulationScreen(t, 40, 10)
+	m.Draw(screen)
+	theme := windows[2].currentTheme()
+	for i, want := range []tcell.Style{theme.BorderUrgent, theme.Border, theme.BorderUrgent} {
+		x, y, _, _ := windows[i].GetRect()
+		if got := cellStyle(screen, x, y); got != want {
+			t.Errorf("the border of window %d is drawn with %v, want %v", i, got, want)
+		}
+	}
+
+	windows[2].SetUrgent(false)
+	if !windows[0].IsUrgent() || windows[2].IsUrgent() {
+		t.Error("the urgent flags are wrong")
+	}
+}
+
+func TestFocusUrgent(t *testing.T) {
+	m, windows := newUrgentManager()
+	focus := &focuser{}
+
+	if m.FocusUrgent() != nil {
+		t.Error("a window was focused without urgent window")
+	}
+
+	// the urgent window is shown again and focused with the manager
+	m.Maximize(windows[0])
+	windows[1].SetUrgent(true)
+	if m.FocusUrgent() != windows[1] || m.IsMaximazed(windows[0]) {
+		t.Fatal("the urgent window was not made visible")
+	}
+	focus.setFocus(m)
+	if !windows[1].HasFocus() || windows[1].IsUrgent() {
+		t.Error("the urgent window was not focused")
+	}
+
+	// with the action
+	m.SetKeybinding(ActionFocusUrgent, KeyBinding{Key: tcell.KeyCtrlU})
+	windows[2].SetUrgent(true)
+	m.InputHandler()(tcell.NewEventKey(tcell.KeyCtrlU, 0, tcell.ModNone), focus.setFocus)
+	if !windows[2].HasFocus() || windows[2].IsUrgent() {
+		t.Error("the action did not focus the urgent window")
+	}
+}
+
+func TestUrgentFunc(t *testing.T) {
+	m, windows := newUrgentManager()
+	focus := &focuser{}
+	screen := newSimulationScreen(t, 40, 10)
+
+	var calls [][]*Window
+	m.SetUrgentFunc(func(windows []*Window) {
+		calls = append(calls, windows)
+	})
+
+	windows[0].SetUrgent(true)
+	m.Draw(screen)
+	m.Draw(screen)
+	windows[2].SetUrgent(true)
+	windows[2].urgentSince = windows[0].urgentSince.Add(time.Second)
+	m.Draw(screen)
+	focus.setFocus(windows[0])
+	m.Draw(screen)
+
+	want := [][]*Window{
+		{windows[0]},
+		{windows[0], windows[2]},
+		{windows[2]},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("the urgent handler was called with %v, want %v", calls, want)
+	}
+}
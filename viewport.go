@@ -0,0 +1,288 @@
+package tilman
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// scrollDrag is the scrollbar whose thumb is dragged with the mouse
+type scrollDrag int
+
+const (
+	dragNone scrollDrag = iota
+	dragVertical
+	dragHorizontal
+)
+
+// SetVirtualSize sets the size of the canvas the root primitive is drawn on. If
+// it is larger than the window, the window shows a part of it which is
+// scrolled with the mouse wheel, Shift with the arrow keys, Page Up/Down and
+// Home/End, or by dragging the thumbs of the scrollbars. A size of zero fits
+// the window in that direction.
+func (w *Window) SetVirtualSize(width, height int) *Window {
+	w.virtualWidth, w.virtualHeight = width, height
+	return w
+}
+
+// GetVirtualSize returns the size of the canvas the root primitive is drawn on
+func (w *Window) GetVirtualSize() (int, int) {
+	return w.virtualWidth, w.virtualHeight
+}
+
+// SetScrollOffset sets the position of the visible part of the canvas
+func (w *Window) SetScrollOffset(x, y int) *Window {
+	w.scrollX, w.scrollY = x, y
+	w.clampScroll()
+	return w
+}
+
+// GetScrollOffset returns the position of the visible part of the canvas
+func (w *Window) GetScrollOffset() (int, int) {
+	return w.scrollX, w.scrollY
+}
+
+// ScrollTo scrolls the least needed to make the given point of the canvas
+// visible
+func (w *Window) ScrollTo(x, y int) *Window {
+	_, _, width, height := w.GetInnerRect()
+	if x < w.scrollX {
+		w.scrollX = x
+	} else if x >= w.scrollX+width {
+		w.scrollX = x - width + 1
+	}
+	if y < w.scrollY {
+		w.scrollY = y
+	} else if y >= w.scrollY+height {
+		w.scrollY = y - height + 1
+	}
+	w.clampScroll()
+	return w
+}
+
+// scrollbars returns whether the vertical and the horizontal scrollbars are
+// needed for a content area of the given size. Reserved scrollbars take space
+// from the content area which may make the other one needed.
+func (w *Window) scrollbars(width, height int, reserve bool) (bool, bool) {
+	vertical := w.virtualHeight > height
+	if vertical && reserve {
+		width--
+	}
+	horizontal := w.virtualWidth > width
+	if horizontal && reserve && !vertical {
+		height--
+		vertical = w.virtualHeight > height
+	}
+	return vertical, horizontal
+}
+
+// clampScroll keeps the scroll offset within the canvas
+func (w *Window) clampScroll() {
+	_, _, width, height := w.GetInnerRect()
+	w.scrollX = clamp(w.scrollX, 0, w.virtualWidth-width)
+	w.scrollY = clamp(w.scrollY, 0, w.virtualHeight-height)
+}
+
+// clamp returns the value limited to [low, high], low if high is below it
+func clamp(value, low, high int) int {
+	if value > high {
+		value = high
+	}
+	if value < low {
+		value = low
+	}
+	return value
+}
+
+// canvasRect returns the rectangle of the root primitive for the given content
+// area, moved by the scroll offset
+func (w *Window) canvasRect(x, y, width, height int) (int, int, int, int) {
+	w.clampScroll()
+	if w.virtualWidth > width {
+		x, width = x-w.scrollX, w.virtualWidth
+	}
+	if w.virtualHeight > height {
+		y, height = y-w.scrollY, w.virtualHeight
+	}
+	return x, y, width, height
+}
+
+// thumb returns the position and the length of the thumb of a scrollbar as
+// long as the visible part of the canvas
+func thumb(track, canvas, offset int) (int, int) {
+	if canvas <= track {
+		return 0, track
+	}
+
+	length := track * track / canvas
+	if length < 1 {
+		length = 1
+	}
+	return (track - length) * offset / (canvas - track), length
+}
+
+// drawScrollbars draws the scrollbars to the right and below the content area
+func (w *Window) drawScrollbars(screen tcell.Screen, x, y, width, height int) {
+	if !w.verticalBar && !w.horizontalBar {
+		return
+	}
+
+	theme, borders := w.currentTheme(), orDefaultBorders(w.inheritedBorders)
+	track, bar := theme.Border, theme.Button
+	if w.Box.HasFocus() {
+		track = theme.BorderFocused
+	}
+
+	if w.verticalBar {
+		position, length := thumb(height, w.virtualHeight, w.scrollY)
+		for i := 0; i < height; i++ {
+			glyph, style := borders.Vertical, track
+			if i >= position && i < position+length {
+				glyph, style = '█', bar
+			}
+			screen.SetContent(x+width, y+i, glyph, nil, style)
+		}
+	}
+
+	if w.horizontalBar {
+		position, length := thumb(width, w.virtualWidth, w.scrollX)
+		for i := 0; i < width; i++ {
+			glyph, style := borders.Horizontal, track
+			if i >= position && i < position+length {
+				glyph, style = '█', bar
+			}
+			screen.SetContent(x+i, y+height, glyph, nil, style)
+		}
+	}
+}
+
+// scrollBy moves the scroll offset, it returns true if the offset changed, so
+// that the keys and the wheel reach the root once the canvas ends
+func (w *Window) scrollBy(dx, dy int) bool {
+	if w.virtualWidth == 0 && w.virtualHeight == 0 {
+		return false
+	}
+
+	x, y := w.scrollX, w.scrollY
+	w.scrollX += dx
+	w.scrollY += dy
+	w.clampScroll()
+	return w.scrollX != x || w.scrollY != y
+}
+
+// handleScrollKey scrolls with Shift and the navigation keys, it returns true
+// if the key was consumed
+func (w *Window) handleScrollKey(event *tcell.EventKey) bool {
+	if event.Modifiers()&tcell.ModShift == 0 {
+		return false
+	}
+
+	_, _, width, height := w.GetInnerRect()
+	switch event.Key() {
+	case tcell.KeyUp:
+		return w.scrollBy(0, -1)
+	case tcell.KeyDown:
+		return w.scrollBy(0, 1)
+	case tcell.KeyLeft:
+		return w.scrollBy(-1, 0)
+	case tcell.KeyRight:
+		return w.scrollBy(1, 0)
+	case tcell.KeyPgUp:
+		return w.scrollBy(0, -height)
+	case tcell.KeyPgDn:
+		return w.scrollBy(0, height)
+	case tcell.KeyHome:
+		return w.scrollBy(-w.virtualWidth-width, -w.virtualHeight-height)
+	case tcell.KeyEnd:
+		return w.scrollBy(w.virtualWidth+width, w.virtualHeight+height)
+	}
+
+	return false
+}
+
+// handleScrollMouse scrolls with the mouse wheel, pages with clicks on the
+// scrollbar tracks and drags the thumbs. It returns true if the event was
+// consumed.
+func (w *Window) handleScrollMouse(action tview.MouseAction, event *tcell.EventMouse) bool {
+	x, y, width, height := w.GetInnerRect()
+	mouseX, mouseY := event.Position()
+
+	// dragging goes on outside the window
+	if w.scrollDrag != dragNone {
+		switch action {
+		case tview.MouseMove:
+			if w.scrollDrag == dragVertical {
+				_, length := thumb(height, w.virtualHeight, w.scrollY)
+				w.scrollY = dragOffset(mouseY-y-w.scrollGrab, height-length, w.virtualHeight-height)
+			} else {
+				_, length := thumb(width, w.virtualWidth, w.scrollX)
+				w.scrollX = dragOffset(mouseX-x-w.scrollGrab, width-length, w.virtualWidth-width)
+			}
+			w.clampScroll()
+		case tview.MouseLeftUp:
+			w.scrollDrag = dragNone
+		}
+		return true
+	}
+
+	if !w.InRect(mouseX, mouseY) {
+		return false
+	}
+
+	switch action {
+	case tview.MouseScrollUp:
+		return w.virtualHeight > height && w.scrollBy(0, -1)
+	case tview.MouseScrollDown:
+		return w.virtualHeight > height && w.scrollBy(0, 1)
+	case tview.MouseScrollLeft:
+		return w.virtualWidth > width && w.scrollBy(-1, 0)
+	case tview.MouseScrollRight:
+		return w.virtualWidth > width && w.scrollBy(1, 0)
+	}
+
+	onVertical := w.verticalBar && mouseX == x+width && mouseY >= y && mouseY < y+height
+	onHorizontal := w.horizontalBar && mouseY == y+height && mouseX >= x && mouseX < x+width
+	if !onVertical && !onHorizontal {
+		return false
+	}
+
+	if action == tview.MouseLeftDown {
+		if onVertical {
+			position, length := thumb(height, w.virtualHeight, w.scrollY)
+			w.pressScrollbar(dragVertical, mouseY-y, position, length, height)
+		} else {
+			position, length := thumb(width, w.virtualWidth, w.scrollX)
+			w.pressScrollbar(dragHorizontal, mouseX-x, position, length, width)
+		}
+	}
+
+	// the scrollbars consume all clicks
+	return true
+}
+
+// pressScrollbar grabs the thumb if it was pressed, otherwise pages toward the
+// pressed position
+func (w *Window) pressScrollbar(bar scrollDrag, pressed, position, length, page int) {
+	if pressed >= position && pressed < position+length {
+		w.scrollDrag = bar
+		w.scrollGrab = pressed - position
+		return
+	}
+
+	if pressed < position {
+		page = -page
+	}
+	if bar == dragVertical {
+		w.scrollBy(0, page)
+	} else {
+		w.scrollBy(page, 0)
+	}
+}
+
+// dragOffset returns the scroll offset for the thumb moved to the given
+// position of the track it can move along
+func dragOffset(position, track, scrollable int) int {
+	if track <= 0 {
+		return 0
+	}
+	return position * scrollable / track
+}
@@ -0,0 +1,118 @@
+package tilman
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// newScrolledWindow creates a bordered 10x5 window showing a 20x10 canvas, it
+// counts the keys reaching the root
+func newScrolledWindow(t *testing.T, keys *int) (*Window, tview.Primitive) {
+	root := tview.NewBox().SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		*keys++
+		return event
+	})
+	w := NewWindow().SetRoot(root).SetBorder(true).SetBorderStyle(BorderSingle).SetVirtualSize(20, 10)
+	drawWindow(t, w, 10, 5)
+	return w, root
+}
+
+func TestScrollBy(t *testing.T) {
+	keys := 0
+	w, _ := newScrolledWindow(t, &keys)
+
+	tests := []struct {
+		dx, dy   int
+		scrolled bool
+		x, y     int
+	}{
+		{0, -1, false, 0, 0},
+		{-1, 0, false, 0, 0},
+		{1, 2, true, 1, 2},
+		{100, 100, true, 12, 7},
+		{1, 1, false, 12, 7},
+		{-1, 1, true, 11, 7},
+	}
+	for _, test := range tests {
+		scrolled := w.scrollBy(test.dx, test.dy)
+		if x, y := w.GetScrollOffset(); scrolled != test.scrolled || x != test.x || y != test.y {
+			t.Errorf("scrolling by %d,%d returned %v with the offset %d,%d, want %v with %d,%d",
+				test.dx, test.dy, scrolled, x, y, test.scrolled, test.x, test.y)
+		}
+	}
+
+	if NewWindow().scrollBy(1, 1) {
+		t.Error("a window without canvas scrolled")
+	}
+}
+
+func TestScrollKeys(t *testing.T) {
+	keys := 0
+	w, root := newScrolledWindow(t, &keys)
+	key := func(key tcell.Key, modifiers tcell.ModMask) {
+		w.InputHandler()(tcell.NewEventKey(key, 0, modifiers), func(p tview.Primitive) {})
+	}
+
+	key(tcell.KeyDown, tcell.ModShift)
+	key(tcell.KeyPgDn, tcell.ModShift)
+	if x, y := w.GetScrollOffset(); x != 0 || y != 4 || keys != 0 {
+		t.Errorf("the offset is %d,%d and the root received %d keys, want 0,4 and 0", x, y, keys)
+	}
+
+	// the keys reach the root once the canvas ends
+	key(tcell.KeyEnd, tcell.ModShift)
+	key(tcell.KeyDown, tcell.ModShift)
+	key(tcell.KeyDown, tcell.ModNone)
+	if x, y := w.GetScrollOffset(); x != 12 || y != 7 || keys != 2 {
+		t.Errorf("the offset is %d,%d and the root received %d keys, want 12,7 and 2", x, y, keys)
+	}
+
+	key(tcell.KeyHome, tcell.ModShift)
+	drawWindow(t, w, 10, 5)
+	if x, y, width, height := root.GetRect(); x != 1 || y != 1 || width != 20 || height != 10 {
+		t.Errorf("the root is at %d,%d %dx%d, want 1,1 20x10", x, y, width, height)
+	}
+
+	w.ScrollTo(15, 8)
+	drawWindow(t, w, 10, 5)
+	if x, y, _, _ := root.GetRect(); x != 1-8 || y != 1-6 {
+		t.Errorf("the root is at %d,%d after scrolling to 15,8, want -7,-5", x, y)
+	}
+}
+
+func TestScrollbars(t *testing.T) {
+	keys := 0
+	w, _ := newScrolledWindow(t, &keys)
+	screen := drawWindow(t, w, 10, 5)
+
+	// the thumbs are drawn on the right and the bottom border
+	checkCells(t, screen, "scrollbars", map[[2]int]rune{
+		{9, 1}: '█',
+		{9, 3}: SingleBorderGlyphs.Vertical,
+		{1, 4}: '█',
+		{8, 4}: SingleBorderGlyphs.Horizontal,
+	})
+
+	// the wheel scrolls the canvas, a click on the track pages
+	mouse(w, tview.MouseScrollDown, 4, 2)
+	mouse(w, tview.MouseScrollRight, 4, 2)
+	if x, y := w.GetScrollOffset(); x != 1 || y != 1 {
+		t.Errorf("the offset is %d,%d after the wheel, want 1,1", x, y)
+	}
+	mouse(w, tview.MouseLeftDown, 9, 3)
+	mouse(w, tview.MouseLeftUp, 9, 3)
+	if _, y := w.GetScrollOffset(); y != 4 {
+		t.Errorf("the vertical offset is %d after a click on the track, want 4", y)
+	}
+
+	// dragging the thumb to the end of the track scrolls to the end
+	drawWindow(t, w, 10, 5)
+	mouse(w, tview.MouseLeftDown, 2, 4)
+	mouse(w, tview.MouseMove, 20, 4)
+	mouse(w, tview.MouseLeftUp, 20, 4)
+	if x, _ := w.GetScrollOffset(); x != 12 {
+		t.Errorf("the horizontal offset is %d after dragging the thumb, want 12", x)
+	}
+}
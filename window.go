@@ -1,7 +1,7 @@
 package tilman
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -16,12 +16,23 @@ const (
 
 // WindowButton represents a button on the window title bar
 type WindowButton struct {
+	Name      string // identifies the button in configurations, e.g. "close"
 	Alignment WindowButtonAlignment
-	Symbol    rune // icon for the button
+	Symbol    rune   // icon for the button
+	Label     string // text of the button, replaces the symbol if set
+
+	Tooltip  string // text shown near the button when the mouse rests on it
+	Disabled bool   // a disabled button is drawn but cannot be clicked
+	Hidden   bool   // a hidden button is neither drawn nor takes space
+
+	Key    KeyBinding // shortcut clicking the button when the window has focus
+	Footer bool       // the button is placed on the footer instead of the title bar
 
 	OnClick func(w *Window, b *WindowButton) // callback to be invoked when the button is clicked
 
 	offsetX, offsetY int
+	// width of the button including the brackets
+	width int
 }
 
 // Window defines a basic window
@@ -31,12 +42,76 @@ type Window struct {
 	root tview.Primitive
 	// window buttons on the title bar
 	buttons []*WindowButton
+	// the text drawn around the label of each button
+	buttonBrackets [2]string
+	// entries added by the application to the context menu
+	menuItems []*MenuItem
+	// the size of the layout item before the window was minimized, nil if it
+	// is not minimized
+	minimized *Item
+	// where the window was in the layouts while it floats in a layer
+	docked *dockPosition
+	// left, center and right segments of the footer and whether it is shown
+	footer      [3]string
+	footerShown bool
 	// whether to render a border
 	border bool
+	// the glyphs to render the border with
+	borderStyle *BorderStyle
+	// the glyphs of the manager, used without border style and for scrollbars
+	inheritedBorders *BorderSet
+	// whether the border is collapsed into the lines of the parent layout
+	collapsed bool
 	// The color of the title.
 	titleColor tcell.Color
 	// The alignment of the title.
 	titleAlign int
+	// provides the title on each draw, if set
+	titleFunc func(w *Window) string
+	// how a title too long for the title bar is shortened
+	titleTruncation TitleTruncation
+	// whether the window requires attention, since when and if it blinks
+	urgent      bool
+	urgentSince time.Time
+	urgentBlink bool
+	// transforms the styles of the window when it does not have focus
+	dimTransform func(tcell.Style) tcell.Style
+	// size of the canvas the root is drawn on, zero to fit the window
+	virtualWidth, virtualHeight int
+	// offset of the visible part of the canvas
+	scrollX, scrollY int
+	// whether the scrollbars are drawn
+	verticalBar, horizontalBar bool
+	// the scrollbar whose thumb is dragged and where it was grabbed
+	scrollDrag scrollDrag
+	scrollGrab int
+	// whether the window is drawn from its cache while it is not dirty, the
+	// cache and the state it was drawn in
+	damageTracking bool
+	dirty          bool
+	cache          *Buffer
+	cachedState    drawState
+	// whether the parent was drawn over the window since its last draw
+	exposed bool
+	// name of the factory which recreates the window when a session is restored
+	kind string
+	// application data saved with the session
+	userData interface{}
+	// The theme of the window and the theme inherited from the parent, if any
+	theme, inheritedTheme *Theme
+	// the theme drawn with when there is none and what it was made from
+	fallbackTheme  *Theme
+	fallbackColors fallbackColors
+	// the button under the mouse and the button pressed with the mouse, if any
+	hovered, pressed *WindowButton
+	// the button selected with the keyboard while the title bar has focus
+	selected *WindowButton
+	// when the mouse started hovering the button and the timer showing its tooltip
+	hoverStart   time.Time
+	tooltipTimer *time.Timer
+	// An optional function which is called when the window needs to be redrawn
+	// without user input, e.g. to show a tooltip
+	changed func()
 }
 
 func NewWindow() *Window {
@@ -44,6 +119,8 @@ func NewWindow() *Window {
 		Box:        tview.NewBox(),
 		titleColor: tview.Styles.TitleColor,
 		titleAlign: tview.AlignCenter,
+
+		buttonBrackets: [2]string{"[", "]"},
 	}
 
 	window.Box.SetDrawFunc(window.drawBox)
@@ -62,8 +139,87 @@ func (w *Window) SetRoot(root tview.Primitive) *Window {
 	return w
 }
 
+// GetKind returns the name of the session factory which creates this window
+func (w *Window) GetKind() string {
+	return w.kind
+}
+
+// SetKind sets the name of the session factory which recreates this window
+// when a session is restored. Windows without kind are not saved.
+func (w *Window) SetKind(kind string) *Window {
+	w.kind = kind
+	return w
+}
+
+// GetUserData returns the application data attached to the window
+func (w *Window) GetUserData() interface{} {
+	return w.userData
+}
+
+// SetUserData attaches application data to the window. The data is encoded as
+// JSON when the session is saved and passed to the window factory on restore.
+func (w *Window) SetUserData(data interface{}) *Window {
+	w.userData = data
+	return w
+}
+
+// SetTheme sets the theme of the window, overriding the theme of the window
+// manager. When a theme is set, the background, border and title colors of the
+// window are taken from the theme.
+func (w *Window) SetTheme(theme *Theme) *Window {
+	w.theme = theme
+	return w
+}
+
+// GetTheme returns the theme set on the window, if any
+func (w *Window) GetTheme() *Theme {
+	return w.theme
+}
+
+// currentTheme returns the theme the window is drawn with
+func (w *Window) currentTheme() *Theme {
+	if w.theme != nil {
+		return w.theme
+	}
+	if w.inheritedTheme != nil {
+		return w.inheritedTheme
+	}
+
+	// without theme, the colors set on the window are used
+	colors := fallbackColors{
+		styles:           tview.Styles,
+		background:       w.Box.GetBackgroundColor(),
+		border:           w.Box.GetBorderColor(),
+		borderAttributes: w.Box.GetBorderAttributes(),
+		title:            w.titleColor,
+	}
+	if w.fallbackTheme != nil && w.fallbackColors == colors {
+		return w.fallbackTheme
+	}
+
+	theme := DefaultTheme()
+	theme.Background = colors.background
+	theme.Border = tcell.StyleDefault.
+		Foreground(colors.border).
+		Attributes(colors.borderAttributes)
+	theme.BorderFocused = theme.Border
+	theme.Title = tcell.StyleDefault.Foreground(colors.title)
+	theme.TitleFocused = theme.Title
+
+	w.fallbackTheme, w.fallbackColors = theme, colors
+	return theme
+}
+
+// fallbackColors are the colors the theme of a window without theme is made of
+type fallbackColors struct {
+	styles                    tview.Theme
+	background, border, title tcell.Color
+	borderAttributes          tcell.AttrMask
+}
+
 // Focus is called when this primitive receives focus.
 func (w *Window) Focus(delegate func(p tview.Primitive)) {
+	w.urgent = false
 	if w.root != nil {
 		delegate(w.root)
 	} else {
@@ -80,6 +236,7 @@ func (w *Window) HasFocus() bool {
 }
 
 func (w *Window) Blur() {
+	w.selected = nil
 	if w.root != nil {
 		w.root.Blur()
 	}
@@ -100,7 +257,38 @@ func (w *Window) SetBorder(show bool) *Window {
 	return w
 }
 
-// SetTitle sets the window title
+// SetBorderStyle sets the glyphs the window border is drawn with. A nil style
+// draws only the title bar with the glyphs of the manager (see
+// Manager.SetBorderSet), tview.Borders by default.
+func (w *Window) SetBorderStyle(style *BorderStyle) *Window {
+	w.borderStyle = style
+	return w
+}
+
+// GetBorderStyle returns the border style set on the window, if any
+func (w *Window) GetBorderStyle() *BorderStyle {
+	return w.borderStyle
+}
+
+// currentBorderStyle returns the border style the window is drawn with, only
+// the title bar is drawn when the border is collapsed
+func (w *Window) currentBorderStyle() *BorderStyle {
+	style := w.borderStyle
+	if style == nil {
+		style = defaultBorderStyle(orDefaultBorders(w.inheritedBorders))
+	}
+
+	if w.collapsed && !style.TitleOnly {
+		collapsed := *style
+		collapsed.TitleOnly = true
+		return &collapsed
+	}
+
+	return style
+}
+
+// SetTitle sets the window title, it may contain tview color tags such as
+// "[red]build failed[-]"
 func (w *Window) SetTitle(text string) *Window {
 	w.Box.SetTitle(text)
 	return w
@@ -110,43 +298,61 @@ func (w *Window) SetTitle(text string) *Window {
 func (w *Window) Draw(screen tcell.Screen) {
 	if w.HasFocus() { // if the window has focus, make sure the underlying box shows a thicker border
 		w.Box.Focus(nil)
+		w.urgent = false
 	} else {
 		w.Box.Blur()
+		if w.dimTransform != nil {
+			screen = NewStyleScreen(screen, w.dimTransform)
+		}
 	}
-	w.Box.Draw(screen) // draw the window frame
 
-	// draw the underlying root primitive within the window bounds
+	if w.damageTracking {
+		w.drawCached(screen)
+		return
+	}
+	w.draw(screen)
+}
+
+// draw draws the frame, the content and the buttons of the window
+func (w *Window) draw(screen tcell.Screen) {
+	// draw the window frame, the border is drawn by drawBox and not with the
+	// glyphs of tview.Borders
+	w.Box.SetBorder(false)
+	w.Box.Draw(screen)
+	w.Box.SetBorder(w.border)
+
+	// draw the underlying root primitive within the window bounds, moved by
+	// the scroll offset if it is larger than the window
+	x, y, width, height := w.GetInnerRect()
 	if w.root != nil {
-		x, y, width, height := w.GetInnerRect()
-		w.root.SetRect(x, y, width, height)
+		w.root.SetRect(w.canvasRect(x, y, width, height))
 		w.root.Draw(NewClipRegion(screen, x, y, width, height))
 	}
+	w.drawScrollbars(screen, x, y, width, height)
 
-	// draw the window border
-	if w.border {
+	// draw the window buttons
+	if w.border || w.footerShown {
 		x, y, width, height := w.GetRect()
 		screen = NewClipRegion(screen, x, y, width, height)
-		for _, button := range w.buttons {
-			buttonX, buttonY := button.offsetX+x, button.offsetY+y
-			if button.offsetX < 0 {
-				buttonX += width
-			}
-			if button.offsetY < 0 {
-				buttonY += height
-			}
-
-			// render the window title buttons
-			tview.Print(screen, tview.Escape(fmt.Sprintf("[%c]", button.Symbol)), buttonX-1, buttonY, 9, 0, tcell.ColorYellow)
-		}
+		w.drawButtons(screen)
 	}
 }
 
+// SetChangedFunc sets a handler which is called when the window needs to be
+// redrawn without user input, e.g. to show the tooltip of a button. Usually
+// the handler calls Application.Draw.
+func (w *Window) SetChangedFunc(handler func()) *Window {
+	w.changed = handler
+	return w
+}
+
 func (w *Window) drawBox(screen tcell.Screen, x, y, width, height int) (int, int, int, int) {
 	def := tcell.StyleDefault
+	theme := w.currentTheme()
 
 	// Fill background.
-	background := def.Background(w.Box.GetBackgroundColor())
-	if w.Box.GetBackgroundColor() != tcell.ColorDefault {
+	background := def.Background(theme.Background)
+	if theme.Background != tcell.ColorDefault {
 		for y_ := y; y_ < y+height; y_++ {
 			for x_ := x; x_ < x+width; x_++ {
 				screen.SetContent(x_, y_, ' ', nil, background)
@@ -154,35 +360,96 @@ func (w *Window) drawBox(screen tcell.Screen, x, y, width, height int) (int, int
 		}
 	}
 
+	style := w.currentBorderStyle()
+	focused := w.Box.HasFocus()
+	glyphs := style.glyphs(focused)
+	borderStyle, titleStyle := theme.Border, theme.Title
+	if focused {
+		borderStyle, titleStyle = theme.BorderFocused, theme.TitleFocused
+	}
+	if w.urgent {
+		borderStyle, titleStyle = theme.BorderUrgent, theme.TitleUrgent
+		if w.urgentBlink {
+			borderStyle, titleStyle = borderStyle.Blink(true), titleStyle.Blink(true)
+		}
+	}
+
 	if w.border && width >= 2 && height >= 2 {
-		var horizontal rune
-		if w.Box.HasFocus() {
-			horizontal = tview.Borders.HorizontalFocus
+		if style.TitleOnly {
+			for x_ := x; x_ < x+width; x_++ {
+				screen.SetContent(x_, y, glyphs.Horizontal, nil, borderStyle)
+			}
 		} else {
-			horizontal = tview.Borders.Horizontal
-		}
-		borderStyle := tcell.StyleDefault.Foreground(tview.Styles.BorderColor)
-		borderStyle = borderStyle.Attributes(w.Box.GetBorderAttributes()).Foreground(w.Box.GetBorderColor())
-		for x_ := x; x_ < x+width; x_++ {
-			screen.SetContent(x_, y, horizontal, nil, borderStyle)
+			for x_ := x + 1; x_ < x+width-1; x_++ {
+				screen.SetContent(x_, y, glyphs.Horizontal, nil, borderStyle)
+				screen.SetContent(x_, y+height-1, glyphs.Horizontal, nil, borderStyle)
+			}
+			for y_ := y + 1; y_ < y+height-1; y_++ {
+				screen.SetContent(x, y_, glyphs.Vertical, nil, borderStyle)
+				screen.SetContent(x+width-1, y_, glyphs.Vertical, nil, borderStyle)
+			}
+			screen.SetContent(x, y, glyphs.TopLeft, nil, borderStyle)
+			screen.SetContent(x+width-1, y, glyphs.TopRight, nil, borderStyle)
+			screen.SetContent(x, y+height-1, glyphs.BottomLeft, nil, borderStyle)
+			screen.SetContent(x+width-1, y+height-1, glyphs.BottomRight, nil, borderStyle)
 		}
 
-		// Draw title.
-		title := w.Box.GetTitle()
-		titleColor := w.titleColor
-		titleAlign := w.titleAlign
-		if title != "" && width >= 4 {
-			printed, _ := tview.Print(screen, title, x+1, y, width-2, titleAlign, titleColor)
-			if len(title)-printed > 0 && printed > 0 {
-				_, _, style, _ := screen.GetContent(x+width-2, y)
-				fg, _, _ := style.Decompose()
-				tview.Print(screen, string(tview.SemigraphicsHorizontalEllipsis), x+width-2, y, 1, tview.AlignLeft, fg)
+		// Draw title, enclosed by the separators if any.
+		title := w.GetTitle()
+		titleStart, titleEnd := w.freeSpan(false, x+1, x+width-1)
+		titleX, titleWidth := titleStart, titleEnd-titleStart
+		separated := glyphs.TitleLeft != 0 && glyphs.TitleRight != 0
+		if separated {
+			titleX, titleWidth = titleX+1, titleWidth-2
+		}
+		// the ellipsis is part of the printed title, so that a transforming
+		// screen transforms its color once
+		if w.titleTruncation == TruncateMiddle {
+			title = truncateMiddle(title, titleWidth)
+		} else {
+			title = truncateEnd(title, titleWidth)
+		}
+		if title != "" && titleWidth >= 2 {
+			_, printedWidth := styledPrint(screen, title, titleX, y, titleWidth, w.titleAlign, titleStyle)
+
+			if separated && printedWidth > 0 {
+				start := titleX
+				switch w.titleAlign {
+				case tview.AlignCenter:
+					start += (titleWidth - printedWidth) / 2
+				case tview.AlignRight:
+					start += titleWidth - printedWidth
+				}
+				screen.SetContent(start-1, y, glyphs.TitleLeft, nil, borderStyle)
+				screen.SetContent(start+printedWidth, y, glyphs.TitleRight, nil, borderStyle)
 			}
 		}
 	}
 
+	if w.footerShown && height >= 2 {
+		w.drawFooter(screen, x, y, width, height, glyphs, borderStyle, titleStyle)
+	}
+
+	// scrollbars are drawn on the border or take the last column and row of
+	// the content
+	if w.border && !style.TitleOnly {
+		w.verticalBar, w.horizontalBar = w.scrollbars(width-2, height-2, false)
+		return x + 1, y + 1, width - 2, height - 2
+	}
+
 	y += 1
 	height -= 1
+	if w.footerShown {
+		height -= 1
+	}
+
+	w.verticalBar, w.horizontalBar = w.scrollbars(width, height, true)
+	if w.verticalBar {
+		width -= 1
+	}
+	if w.horizontalBar {
+		height -= 1
+	}
 
 	return x, y, width, height
 }
@@ -195,20 +462,36 @@ func (w *Window) AddButton(symbol rune, alignment WindowButtonAlignment, onclick
 		OnClick:   onclick,
 	})
 
-	offsetLeft, offsetRight := 2, -3
-	for _, button := range w.buttons {
-		if button.Alignment == WindowButtonAlignRight {
-			button.offsetX = offsetRight
-			offsetRight -= 3
-		} else {
-			button.offsetX = offsetLeft
-			offsetLeft += 3
-		}
-	}
+	w.layoutButtons()
 
 	return w
 }
 
+// AddLabeledButton adds a new window button showing a text label, e.g. "Save"
+func (w *Window) AddLabeledButton(label string, alignment WindowButtonAlignment, onclick func(w *Window, b *WindowButton)) *Window {
+	w.buttons = append(w.buttons, &WindowButton{
+		Label:     label,
+		Alignment: alignment,
+		OnClick:   onclick,
+	})
+
+	w.layoutButtons()
+
+	return w
+}
+
+// SetButtonBrackets sets the text drawn to the left and the right of the label
+// of each button, "[" and "]" by default. Empty strings draw no brackets.
+func (w *Window) SetButtonBrackets(left, right string) *Window {
+	w.buttonBrackets = [2]string{left, right}
+	return w
+}
+
+// GetButtonBrackets returns the text drawn around the label of each button
+func (w *Window) GetButtonBrackets() (string, string) {
+	return w.buttonBrackets[0], w.buttonBrackets[1]
+}
+
 func (w *Window) RemoveButton(i int) *Window {
 	if i < 0 || i >= len(w.buttons) {
 		return w
@@ -241,27 +524,26 @@ func (w *Window) ClearButtons() *Window {
 // MouseHandler returns a mouse handler for this primitive
 func (w *Window) MouseHandler() func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
 	return w.WrapMouseHandler(func(action tview.MouseAction, event *tcell.EventMouse, setFocus func(p tview.Primitive)) (consumed bool, capture tview.Primitive) {
-		if !w.InRect(event.Position()) {
-			return false, nil
+		if w.InRect(event.Position()) {
+			w.dirty = true
 		}
 
-		if action == tview.MouseLeftClick {
-			x, y := event.Position()
-			wx, wy, width, _ := w.GetRect()
-
-			// check if any window button was pressed
-			// if the window does not have border, it cannot receive button events
-			if y == wy && w.border {
-				for _, button := range w.buttons {
-					if button.offsetX >= 0 && x == wx+button.offsetX || button.offsetX < 0 && x == wx+width+button.offsetX {
-						if button.OnClick != nil {
-							button.OnClick(w, button)
-						}
-						return true, nil
-					}
-				}
+		if consumed := w.handleButtonMouse(action, event); consumed {
+			return true, nil
+		}
+		if consumed := w.handleScrollMouse(action, event); consumed {
+			// keep receiving the mouse while a thumb is dragged, also outside
+			// of the window and its layout
+			if w.scrollDrag != dragNone {
+				return true, w
 			}
+			return true, nil
+		}
+
+		if !w.InRect(event.Position()) {
+			return false, nil
 		}
+
 		// pass on clicks to the root primitive, if any
 		if w.root != nil {
 			return w.root.MouseHandler()(action, event, setFocus)
@@ -273,8 +555,17 @@ func (w *Window) MouseHandler() func(action tview.MouseAction, event *tcell.Even
 
 // InputHandler returns a handler which receives key events when it has focus.
 func (w *Window) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
-	if w.root != nil {
-		return w.root.InputHandler()
-	}
-	return nil
+	return w.WrapInputHandler(func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+		w.dirty = true
+
+		if w.handleButtonKey(event) || w.handleScrollKey(event) {
+			return
+		}
+
+		if w.root != nil {
+			if handler := w.root.InputHandler(); handler != nil {
+				handler(event, setFocus)
+			}
+		}
+	})
 }